@@ -0,0 +1,102 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	applyFile   string
+	applyPrune  bool
+	applyDryRun bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f <manifest>",
+	Short: "Expose the services declared in a manifest file",
+	Long: `Expose the services declared in a YAML or JSON manifest file.
+
+The manifest lists HTTP and TCP services using the same fields as the API.
+Services are matched by name against the ones currently exposed by this node:
+missing services are created, changed ones are updated, and services are
+enabled or disabled to match their "enabled" field (default: true).
+
+Manifest example:
+  http:
+    - name: web
+      domain: app.example.com
+      backendPort: 3000
+      pathLocation: /
+      allowedIps: [192.168.1.0/24]
+  tcp:
+    - name: db
+      backendPort: 5432
+      proto: tcp
+      enabled: false
+
+Required flags:
+  -f, --filename   Manifest file, or "-" to read it from stdin
+
+Optional flags:
+  --prune          Disable exposed services that are not in the manifest
+  --dry-run        Only print the plan, do not change anything
+
+Note:
+  Creating or updating a service with "enabled: false" exposes it briefly
+  before it is disabled, as the API always enables services on creation.`,
+	Example: `  # Preview the changes
+  wiredoor apply -f services.yaml --dry-run
+
+  # Apply and disable anything not listed
+  wiredoor apply -f services.yaml --prune
+
+  # Copy the services of another node
+  wiredoor export > services.yaml && wiredoor apply -f services.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		manifest, err := wiredoor.LoadManifest(applyFile)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().StartProgress("Reading node services...")
+		defer utils.Terminal().StopProgress()
+
+		plan, err := wiredoor.ApplyManifest(ctx, manifest, applyPrune, applyDryRun, func(step wiredoor.PlanStep) {
+			utils.Terminal().UpdateProgress(fmt.Sprintf("Applying %s of %s service '%s'...", step.Action, step.Type, step.Name))
+		})
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		if applyDryRun {
+			utils.Terminal().Section("Plan (dry run, nothing was changed):")
+		} else {
+			utils.Terminal().Section("Manifest applied:")
+		}
+
+		utils.Terminal().Render(plan, func() {
+			wiredoor.PrintPlan(plan)
+		})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVarP(&applyFile, "filename", "f", "", "Manifest file (YAML or JSON), or - for stdin")
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "Disable exposed services that are not in the manifest")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Only print the plan, do not change anything")
+	applyCmd.MarkFlagRequired("filename")
+}
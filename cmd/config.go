@@ -0,0 +1,277 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	tokenFile  string
+	tokenStdin bool
+	revealKey  bool
+	dryRun     bool
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <section.key>",
+	Short: "Print the effective value of a config key",
+	Long: `Print the effective value of a config key, after environment overrides
+and defaults. Keys are written <section>.<key>, e.g. client.keepalive;
+server.* keys belong to the profile in use.
+
+The token is masked unless --reveal is given.`,
+	Example: `  wiredoor config get server.url
+  wiredoor config get client.keepalive
+  wiredoor config get server.token --reveal`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entry, err := wiredoor.GetConfigValue(args[0], revealKey)
+		if err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+		utils.Terminal().Render(entry, func() {
+			utils.Terminal().Println(entry.Value)
+		})
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <section.key> <value>",
+	Short: "Validate and store a config key",
+	Long: `Validate a value and store it in the config file. Use - as the value to
+read it from standard input, which keeps secrets out of the shell history.
+
+The token is stored as [server] token_command and token_encryption require.`,
+	Example: `  wiredoor config set server.path /api
+  wiredoor config set client.keepalive 15
+  wiredoor config set daemon.enabled true
+  wiredoor config set health.my-website.path /healthz
+  cat token.txt | wiredoor config set server.token -`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		value := args[1]
+		if value == "-" {
+			data, err := io.ReadAll(io.LimitReader(os.Stdin, 64*1024))
+			if err != nil {
+				utils.Terminal().Errorf("unable to read value from stdin: %v", err)
+				os.Exit(1)
+			}
+			value = strings.TrimSpace(string(data))
+		}
+
+		if err := wiredoor.SetConfigValue(args[0], value); err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+		utils.Terminal().Printf("%s updated.", args[0])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:     "unset <section.key>",
+	Short:   "Remove a config key so its default applies",
+	Example: `  wiredoor config unset client.proxy`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := wiredoor.UnsetConfigValue(args[0]); err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+		utils.Terminal().Printf("%s removed.", args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the effective config and where each value comes from",
+	Long: `List every known config key with its effective value and its source:
+an environment variable, the config file or the built-in default. Server keys
+are those of the profile in use. The token is masked.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := wiredoor.ListConfig()
+		if err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+
+		utils.Terminal().Render(entries, func() {
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				value := e.Value
+				if value == "" {
+					value = "-"
+				}
+				rows = append(rows, []string{e.Key, value, e.Source})
+			}
+			utils.Terminal().Table([]string{"KEY", "VALUE", "SOURCE"}, rows)
+		})
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and WIREDOOR_* overrides",
+	Long: `Check the config file and the WIREDOOR_<SECTION>_<KEY> environment
+variables for unknown sections or keys and invalid values (URL syntax, numeric
+ranges such as keepalive 0-65535, booleans, ...).
+
+Exits with status 1 when problems are found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		problems := wiredoor.ValidateConfig()
+		if len(problems) == 0 {
+			utils.Terminal().Printf("%s is valid.", wiredoor.GetConfigLocation())
+			return
+		}
+
+		for _, problem := range problems {
+			utils.Terminal().Errorf("%v", problem)
+		}
+		os.Exit(1)
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the config file to the current schema version",
+	Long: `Upgrade the config file to the schema version of this wiredoor release,
+recorded in [meta] version: missing settings are added with their defaults
+and legacy values are converted. The original file is backed up next to it
+first (config.ini.v<version>-<time>.bak).
+
+Commands migrate an outdated config file automatically when they can write
+it; use this command to preview the changes or to migrate as root.
+
+Optional flags:
+  --dry-run    Print the changes without writing anything`,
+	Example: `  # Preview the migration
+  wiredoor config migrate --dry-run
+
+  # Migrate
+  sudo wiredoor config migrate`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		changes, backup, err := wiredoor.MigrateConfig(dryRun)
+		if err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+
+		utils.Terminal().Render(changes, func() {
+			if len(changes) == 0 {
+				utils.Terminal().Printf("%s is up to date (version %d).", wiredoor.GetConfigLocation(), wiredoor.ConfigVersion)
+				return
+			}
+
+			rows := make([][]string, 0, len(changes))
+			for _, c := range changes {
+				rows = append(rows, []string{strconv.Itoa(c.Version), c.Change})
+			}
+			utils.Terminal().Table([]string{"VERSION", "CHANGE"}, rows)
+
+			if dryRun {
+				utils.Terminal().Println("")
+				utils.Terminal().Hint("Dry run, nothing was changed. Run without --dry-run to apply.")
+				return
+			}
+			utils.Terminal().Println("")
+			utils.Terminal().Printf("%s migrated to version %d; the original was saved to %s.", wiredoor.GetConfigLocation(), wiredoor.ConfigVersion, backup)
+		})
+	},
+}
+
+func init() {
+	configCmd.Args = cobra.NoArgs
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configValidateCmd, configMigrateCmd)
+
+	// set and unset create the --profile in use; validate and migrate must
+	// run on a config whose current profile is broken.
+	for _, c := range []*cobra.Command{configSetCmd, configUnsetCmd, configValidateCmd} {
+		c.Annotations = createsProfile
+	}
+	configMigrateCmd.Annotations = inspectsConfig
+
+	configGetCmd.Flags().BoolVar(&revealKey, "reveal", false, "Print the token instead of masking it")
+	configMigrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing anything")
+}
+
+// validateConfigFlags checks the flag combination accepted by 'wiredoor
+// config': the server URL and token go together, and so do the client
+// certificate and key.
+func validateConfigFlags(server, token, clientCert, clientKey string) error {
+	if server == "" && token == "" && clientCert == "" && clientKey == "" {
+		return errors.New("nothing to configure: set --url and --token, or --client-cert and --client-key")
+	}
+
+	if (server == "") != (token == "") {
+		return errors.New("--url and --token must be set together")
+	}
+
+	if (clientCert == "") != (clientKey == "") {
+		return errors.New("--client-cert and --client-key must be set together")
+	}
+
+	if clientCert != "" {
+		return wiredoor.ValidateClientCertificate(clientCert, clientKey)
+	}
+
+	return nil
+}
+
+// addTokenFlags adds --token-file and --token-stdin, which keep the token out
+// of the process list and shell history. See readToken.
+func addTokenFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tokenFile, "token-file", "", "Read the node token from a file")
+	cmd.Flags().BoolVar(&tokenStdin, "token-stdin", false, "Read the node token from standard input")
+}
+
+// readToken returns the token given with --token, --token-file or
+// --token-stdin. At most one of them may be used.
+func readToken(token string) (string, error) {
+	sources := 0
+	for _, set := range []bool{token != "", tokenFile != "", tokenStdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.New("use only one of --token, --token-file and --token-stdin")
+	}
+
+	switch {
+	case tokenFile != "":
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read token file: %w", err)
+		}
+		token = string(data)
+	case tokenStdin:
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 64*1024))
+		if err != nil {
+			return "", fmt.Errorf("unable to read token from stdin: %w", err)
+		}
+		token = string(data)
+	default:
+		return token, nil
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("the token read is empty")
+	}
+	return token, nil
+}
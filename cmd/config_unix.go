@@ -15,8 +15,11 @@ import (
 )
 
 var (
-	server string
-	token  string
+	server          string
+	token           string
+	clientCert      string
+	clientKey       string
+	tokenEncryption string
 )
 
 var configCmd = &cobra.Command{
@@ -32,42 +35,105 @@ It is useful for:
   - Preparing a node before establishing a connection
   - Changing the server or token without reconnecting immediately
 
+Token input and storage:
+  --token is visible in the process list and shell history. Prefer
+  --token-file or --token-stdin. The token can also be kept out of
+  config.ini with a credential helper ([server] token_command), or stored
+  encrypted with --token-encryption:
+    machine-id   key derived from /etc/machine-id; only this host can decrypt it
+    passphrase   key derived from $WIREDOOR_TOKEN_PASSPHRASE, needed by every command
+    none         plain text (default)
+
+Mutual TLS:
+  If the Wiredoor server requires client certificates, set --client-cert and
+  --client-key. Relative paths are resolved against the config directory, so
+  the files can be stored next to config.ini.
+
+Other settings:
+  wiredoor config get <section.key>           Print the effective value of a key
+  wiredoor config set <section.key> <value>   Validate and store a key
+  wiredoor config unset <section.key>         Remove a key so its default applies
+  wiredoor config list                        Show all settings and their sources
+  wiredoor config validate                    Check for typos and invalid values
+
 Note:
   This command does NOT connect to the server or establish the VPN tunnel.
   Use 'wiredoor connect' after configuring.
 
 Examples:
   wiredoor config --url=https://wiredoor.example.com --token=ABCDEF123456
+  wiredoor config --client-cert=node.crt --client-key=node.key
+  wiredoor config --token-encryption=machine-id
 
 Afterwards, simply run:
   wiredoor connect`,
 	Example: `  # Configure the Wiredoor server and token
   wiredoor config --url=https://wiredoor.example.com --token=ABCDEF123456
 
+  # Read the token from a secret file
+  wiredoor config --url=https://wiredoor.example.com --token-file=/run/secrets/wiredoor_token
+
+  # Pipe the token from a secret manager and store it encrypted
+  vault kv get -field=token secret/wiredoor | wiredoor config --url=https://wiredoor.example.com --token-stdin --token-encryption=machine-id
+
+  # Authenticate with a client certificate stored next to config.ini
+  wiredoor config --client-cert=node.crt --client-key=node.key
+
   # Then connect when ready
   wiredoor connect`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// utils.Terminal().Println("Saving Wiredoor config to", server)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := readToken(token)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		if err := wiredoor.ValidateTokenEncryption(tokenEncryption); err != nil {
+			return failed(cmd, err)
+		}
 
-		utils.Terminal().StartProgress(fmt.Sprintf("Saving Wiredoor config to %s", server))
+		// --token-encryption alone re-encrypts the stored token.
+		onlyEncryption := tokenEncryption != "" && server == "" && token == "" && clientCert == "" && clientKey == ""
+		if !onlyEncryption {
+			if err := validateConfigFlags(server, token, clientCert, clientKey); err != nil {
+				return failed(cmd, err)
+			}
+		}
+
+		utils.Terminal().StartProgress(fmt.Sprintf("Saving Wiredoor config to %s", wiredoor.GetConfigLocation()))
 		defer utils.Terminal().StopProgress()
 
-		err := wiredoor.SaveServerConfig(server, token)
-		if err != nil {
-			utils.Terminal().Errorf("unable to save config file: %v", err)
-			return
+		// Set the storage mode first, so a new token is stored with it.
+		if tokenEncryption != "" {
+			if err := wiredoor.SetTokenEncryption(tokenEncryption); err != nil {
+				return failed(cmd, fmt.Errorf("unable to change token encryption: %w", err))
+			}
+		}
+
+		if server != "" {
+			if err := wiredoor.SaveServerConfig(server, token); err != nil {
+				return failed(cmd, fmt.Errorf("unable to save config file: %w", err))
+			}
+		}
+
+		if clientCert != "" {
+			if err := wiredoor.SaveClientCertificate(clientCert, clientKey); err != nil {
+				return failed(cmd, fmt.Errorf("unable to save config file: %w", err))
+			}
 		}
 		utils.Terminal().FinalizeProgress()
 		utils.Terminal().Println("Configuration saved to " + wiredoor.GetConfigLocation())
+		return nil
 	},
 }
 
 func init() {
-	configCmd.Flags().StringVar(&server, "url", "", "Wiredoor server URL (required)")
-	configCmd.Flags().StringVar(&token, "token", "", "Node authentication token (required)")
-
-	_ = configCmd.MarkFlagRequired("url")
-	_ = configCmd.MarkFlagRequired("token")
+	configCmd.Annotations = createsProfile
+	configCmd.Flags().StringVar(&server, "url", "", "Wiredoor server URL")
+	configCmd.Flags().StringVar(&token, "token", "", "Node authentication token")
+	addTokenFlags(configCmd)
+	configCmd.Flags().StringVar(&tokenEncryption, "token-encryption", "", "Store the token encrypted: machine-id, passphrase or none")
+	configCmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS (relative to the config directory)")
+	configCmd.Flags().StringVar(&clientKey, "client-key", "", "PEM private key for the client certificate")
 
 	rootCmd.Flags().SortFlags = false
 
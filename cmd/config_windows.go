@@ -19,8 +19,10 @@ import (
 )
 
 var (
-	server string
-	token  string
+	server     string
+	token      string
+	clientCert string
+	clientKey  string
 )
 
 var configCmd = &cobra.Command{
@@ -36,18 +38,34 @@ It is useful for:
   - Preparing a node before establishing a connection
   - Changing the server or token without reconnecting immediately
 
+Mutual TLS:
+  If the Wiredoor server requires client certificates, set --client-cert and
+  --client-key. Relative paths are resolved against the config directory, so
+  the files can be stored next to config.ini.
+
+Other settings:
+  wiredoor config get <section.key>           Print the effective value of a key
+  wiredoor config set <section.key> <value>   Validate and store a key
+  wiredoor config unset <section.key>         Remove a key so its default applies
+  wiredoor config list                        Show all settings and their sources
+  wiredoor config validate                    Check for typos and invalid values
+
 Note:
   This command does NOT connect to the server or establish the VPN tunnel.
   Use 'wiredoor connect' after configuring.
 
 Examples:
   wiredoor config --url=https://wiredoor.example.com --token=ABCDEF123456
+  wiredoor config --client-cert=node.crt --client-key=node.key
 
 Afterwards, simply run:
   wiredoor connect`,
 	Example: `  # Configure the Wiredoor server and token
   wiredoor config --url=https://wiredoor.example.com --token=ABCDEF123456
 
+  # Authenticate with a client certificate stored next to config.ini
+  wiredoor config --client-cert=node.crt --client-key=node.key
+
   # Then connect when ready
   wiredoor connect`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -62,10 +80,23 @@ Afterwards, simply run:
 			os.Exit(1)
 		}
 
+		token, err := readToken(token)
+		if err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+
+		if err := validateConfigFlags(server, token, clientCert, clientKey); err != nil {
+			utils.Terminal().Errorf("%v", err)
+			os.Exit(1)
+		}
+
 		jsonToSend := make(map[string]interface{})
 		jsonToSend["command"] = "config"
 		jsonToSend["url"] = server
 		jsonToSend["token"] = token
+		jsonToSend["client_cert"] = clientCert
+		jsonToSend["client_key"] = clientKey
 
 		utils.Terminal().StartProgress("Saving config...")
 		// IPC does not permit update progress in an easy way
@@ -104,11 +135,12 @@ Afterwards, simply run:
 }
 
 func init() {
-	configCmd.Flags().StringVar(&server, "url", "", "Wiredoor server URL (required)")
-	configCmd.Flags().StringVar(&token, "token", "", "Node authentication token (required)")
-
-	_ = configCmd.MarkFlagRequired("url")
-	_ = configCmd.MarkFlagRequired("token")
+	configCmd.Annotations = createsProfile
+	configCmd.Flags().StringVar(&server, "url", "", "Wiredoor server URL")
+	configCmd.Flags().StringVar(&token, "token", "", "Node authentication token")
+	addTokenFlags(configCmd)
+	configCmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS (relative to the config directory)")
+	configCmd.Flags().StringVar(&clientKey, "client-key", "", "PEM private key for the client certificate")
 
 	rootCmd.Flags().SortFlags = false
 
@@ -7,10 +7,17 @@ Copyright © 2024 Daniel Mesa <support@wiredoor.net>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/wiredoor"
 )
 
+var connectAll bool
+
 var connectCmd = &cobra.Command{
 	Use:   "connect",
 	Short: "Establish a VPN connection to a Wiredoor server",
@@ -24,6 +31,9 @@ This is the standard way to initiate a Wiredoor tunnel after the node has alread
 Optional flags:
   --url           Override the server URL defined in the config file
   --token         Override the node token defined in the config file
+  --token-file    Read the node token from a file instead of --token
+  --token-stdin   Read the node token from standard input instead of --token
+  --all           Connect every server profile that has a URL, each on its own interface
 	--daemon        Enable Wiredoor daemon to keep the connection alive and allow remote control (default)
 	--no-daemon     Disable automatic daemon startup after this command
 
@@ -37,18 +47,53 @@ Typical usage:
   wiredoor connect --url https://wiredoor.example.com
 
   # Provide a custom token (e.g., for automation)
-  wiredoor connect --token=ABCDEF123456`,
-	Run: func(cmd *cobra.Command, args []string) {
+  wiredoor connect --token=ABCDEF123456
+
+  # Connect to every configured server
+  wiredoor connect --all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
 		url, _ := cmd.Flags().GetString("url")
 		token, _ := cmd.Flags().GetString("token")
+		token, err := readToken(token)
+		if err != nil {
+			return failed(cmd, err)
+		}
 		useDaemon, _ := cmd.Flags().GetBool("daemon")
 		setDaemon := cmd.Flags().Changed("daemon")
+		connection := wiredoor.ConnectionConfig{URL: url, Token: token, UseDaemon: useDaemon, SetDaemon: setDaemon}
+
+		if !connectAll {
+			if err := connectTunnel(ctx, connection); err != nil {
+				return failed(cmd, err)
+			}
+			return nil
+		}
 
-		if !wiredoor.WireguardInterfaceExists() {
-			wiredoor.Connect(wiredoor.ConnectionConfig{URL: url, Token: token, UseDaemon: useDaemon, SetDaemon: setDaemon})
-		} else {
-			wiredoor.Status()
+		if url != "" || token != "" {
+			return failed(cmd, errors.New("--url and --token cannot be used with --all"))
 		}
+
+		// A failing profile does not stop the others from connecting.
+		failures := 0
+		err = forEachProfile(func(p wiredoor.Profile) {
+			if p.Url == "" {
+				return
+			}
+			utils.Terminal().Section(fmt.Sprintf("Profile %s (%s)", p.Name, p.Interface))
+			if err := connectTunnel(ctx, connection); err != nil {
+				reportError(err)
+				failures++
+			}
+		})
+		if err != nil {
+			return failed(cmd, err)
+		}
+		if failures > 0 {
+			return exitError(cmd)
+		}
+		return nil
 	},
 }
 
@@ -57,5 +102,21 @@ func init() {
 
 	connectCmd.Flags().String("url", "", "Wiredoor server URL (optional, overrides config file)")
 	connectCmd.Flags().String("token", "", "Node connection token (optional, overrides config file)")
+	addTokenFlags(connectCmd)
 	connectCmd.Flags().Bool("daemon", true, "Enable Wiredoor daemon mode (use --no-daemon to disable)")
+	connectCmd.Flags().BoolVar(&connectAll, "all", false, "Connect every server profile")
+}
+
+// connectTunnel brings up the tunnel of the profile in use, or shows its
+// status when it is already up.
+func connectTunnel(ctx context.Context, connection wiredoor.ConnectionConfig) error {
+	if err := wiredoor.CheckTunnelOwner(); err != nil {
+		return err
+	}
+
+	if !wiredoor.WireguardInterfaceExists() {
+		return wiredoor.Connect(ctx, connection)
+	}
+	wiredoor.Status(ctx)
+	return nil
 }
@@ -48,6 +48,8 @@ Typical usage:
   wiredoor connect --token=ABCDEF123456`,
 
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		url, _ := cmd.Flags().GetString("url")
 		token, _ := cmd.Flags().GetString("token")
 		useDaemon, _ := cmd.Flags().GetBool("daemon")
@@ -84,10 +86,10 @@ Typical usage:
 				if response, ok := jsonResponse["response"].(string); ok {
 					switch response {
 					case "ok":
-						wiredoor.Status()
+						wiredoor.Status(ctx)
 						os.Exit(0)
 					case "Already Connected":
-						wiredoor.Status()
+						wiredoor.Status(ctx)
 						os.Exit(0)
 					default:
 						utils.Terminal().Printf("Error: %v", response)
@@ -0,0 +1,173 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	contextUrl       string
+	contextToken     string
+	contextInterface string
+	contextUse       bool
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage server profiles (contexts)",
+	Long: `Manage the Wiredoor server profiles stored in the config file.
+
+Each profile holds a server URL, a node token and the other [server] settings,
+so one machine can work against several Wiredoor servers (e.g. staging and
+production). The [server] section is the "default" profile; the others are
+stored in [profile <name>] sections.
+
+The profile used by a command is, in order:
+  1. the --profile flag
+  2. the WIREDOOR_PROFILE environment variable
+  3. the current profile set with 'wiredoor context use'
+  4. the default profile
+
+Commands that write server settings ('wiredoor config', 'wiredoor login')
+write to the profile in use, creating it if needed.
+
+Each profile has its own WireGuard interface (the "interface" key, default:
+` + utils.TunnelName + `). Give profiles different interfaces to keep their tunnels up at
+the same time, and use 'wiredoor connect --all' to connect all of them.`,
+	Example: `  # Add a staging server and switch to it
+  wiredoor context add staging --url https://staging.wiredoor.example.com --token ABC123 --use
+
+  # Add a second server with its own tunnel
+  wiredoor context add lab --url https://lab.example.com --token DEF456 --interface wd-lab
+
+  # List profiles
+  wiredoor context list
+
+  # Run a single command against production
+  wiredoor --profile default status`,
+	Annotations: createsProfile,
+}
+
+var contextListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List server profiles",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := wiredoor.ListProfiles()
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().Render(profiles, func() {
+			rows := make([][]string, 0, len(profiles))
+			for _, p := range profiles {
+				current, url := "", p.Url
+				if p.Current {
+					current = "*"
+				}
+				if url == "" {
+					url = "-"
+				}
+				rows = append(rows, []string{current, p.Name, url, p.Interface})
+			}
+			utils.Terminal().Table([]string{"CURRENT", "NAME", "URL", "INTERFACE"}, rows)
+		})
+		return nil
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the profile used when --profile and WIREDOOR_PROFILE are not set",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := wiredoor.UseProfile(args[0]); err != nil {
+			return failed(cmd, err)
+		}
+		utils.Terminal().Printf("Switched to profile '%s'.", args[0])
+		return nil
+	},
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <name> --url <url> --token-file <file>",
+	Short: "Add a server profile, or update its URL and token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		token, err := readToken(contextToken)
+		if err != nil {
+			return failed(cmd, err)
+		}
+		if token == "" {
+			return failed(cmd, errors.New("a node token is required: set --token, --token-file or --token-stdin"))
+		}
+
+		if err := wiredoor.AddProfile(name, contextUrl, token, contextInterface); err != nil {
+			return failed(cmd, err)
+		}
+		utils.Terminal().Printf("Profile '%s' saved.", name)
+
+		if contextUse {
+			if err := wiredoor.UseProfile(name); err != nil {
+				return failed(cmd, err)
+			}
+			utils.Terminal().Printf("Switched to profile '%s'.", name)
+		}
+		return nil
+	},
+}
+
+var contextRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a server profile",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := wiredoor.RemoveProfile(args[0]); err != nil {
+			return failed(cmd, err)
+		}
+		utils.Terminal().Printf("Profile '%s' removed.", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd, contextUseCmd, contextAddCmd, contextRemoveCmd)
+
+	for _, c := range contextCmd.Commands() {
+		c.Annotations = createsProfile
+	}
+
+	contextAddCmd.Flags().StringVar(&contextUrl, "url", "", "Wiredoor server URL")
+	contextAddCmd.Flags().StringVar(&contextToken, "token", "", "Node authentication token")
+	addTokenFlags(contextAddCmd)
+	contextAddCmd.Flags().StringVar(&contextInterface, "interface", "", "WireGuard interface of the profile (default: "+utils.TunnelName+")")
+	contextAddCmd.Flags().BoolVar(&contextUse, "use", false, "Switch to the profile after adding it")
+	contextAddCmd.MarkFlagRequired("url")
+}
+
+// forEachProfile runs fn with each server profile selected in turn, then
+// restores the profile selected with --profile.
+func forEachProfile(fn func(p wiredoor.Profile)) error {
+	profiles, err := wiredoor.ListProfiles()
+	if err != nil {
+		return err
+	}
+
+	defer wiredoor.SetProfile(profile)
+	for _, p := range profiles {
+		wiredoor.SetProfile(p.Name)
+		fn(p)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -13,7 +14,7 @@ import (
 )
 
 var disableCmd = &cobra.Command{
-	Use:   "disable <type> <ID>",
+	Use:   "disable [type] <name|ID>",
 	Short: "Temporarily disable an exposed Wiredoor service",
 	Long: `Temporarily disable a Wiredoor service without deleting its configuration.
 
@@ -23,34 +24,50 @@ preventing external access until it's re-enabled with 'wiredoor enable'.
 It does not remove the service or its configuration, so it can be restored at any time.
 
 Arguments:
-  <type>   The type of service to disable: "http" or "tcp"
-  <ID>     The ID of the service to disable (check services ID with 'wiredoor status')
+  [type]        Optional service type: "http" or "tcp". Inferred when omitted.
+  <name|ID>     The name given to 'wiredoor http/tcp <name>', or the service ID
+                (see 'wiredoor ls')
+
+If a name matches both an HTTP and a TCP service, the command fails and lists
+the candidates; add the type to pick one.
 
 Examples:
+  wiredoor disable my-website
+  wiredoor disable tcp db-access
   wiredoor disable http 4
-  wiredoor disable tcp 5
 
 Use 'wiredoor enable' to re-enable the service later.`,
 	Example: `  # Disable a public website temporarily
-  wiredoor disable http 4
+  wiredoor disable my-website
 
   # Disable a TCP-exposed database service
   wiredoor disable tcp 5`,
-	Args: cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		serviceType := args[0]
-		serviceId := args[1]
-
-		if serviceType != "http" && serviceType != "tcp" {
-			utils.Terminal().Println("Invalid service type. Must be 'http' or 'tcp'.")
-			return
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		utils.Terminal().StartProgress("Looking up service...")
+		defer utils.Terminal().StopProgress()
+
+		target, err := resolveServiceArgs(ctx, args)
+
+		if err != nil {
+			return failed(cmd, err)
 		}
 
-		utils.Terminal().StartProgress(fmt.Sprintf("Disabling %s service '%s'...\n", strings.ToUpper(serviceType), serviceId))
+		utils.Terminal().UpdateProgress(fmt.Sprintf("Disabling %s service '%s'...\n", strings.ToUpper(target.Type), target.Name()))
 
-		defer utils.Terminal().StopProgress()
+		service, err := wiredoor.DisableServiceByType(ctx, target.Type, strconv.FormatInt(target.ID(), 10))
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		utils.Terminal().Section("Service Disabled Successfully!")
 
-		wiredoor.DisableServiceByType(serviceType, serviceId)
+		utils.Terminal().Render(service, service.Print)
+		return nil
 	},
 }
 
@@ -7,10 +7,15 @@ Copyright © 2024 Daniel Mesa <support@wiredoor.net>
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/wiredoor"
 )
 
+var disconnectAll bool
+
 var disconnectCmd = &cobra.Command{
 	Use:   "disconnect",
 	Short: "Disconnect this node from the Wiredoor server",
@@ -24,17 +29,61 @@ Typical use cases:
   - Restarting the tunnel
   - Preparing the node for maintenance
 
+Only the tunnel of the profile in use is stopped, unless --all is given.
+
 Note:
   This does NOT delete the node or token from the Wiredoor server. Use 'wiredoor disable' if you only want to stop a specific service.
 
+Optional flags:
+  --all           Disconnect the tunnels of every server profile
+
 Examples:
   wiredoor disconnect
+  wiredoor disconnect --all
   wiredoor disconnect && sleep 5 && wiredoor connect`,
-	Run: func(cmd *cobra.Command, args []string) {
-		wiredoor.Disconnect()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !disconnectAll {
+			if err := disconnectTunnel(); err != nil {
+				return failed(cmd, err)
+			}
+			return nil
+		}
+
+		disconnected := 0
+		err := forEachProfile(func(p wiredoor.Profile) {
+			// A tunnel shared with another profile is stopped with its owner.
+			if !wiredoor.ExistWireguardConfigFile() || wiredoor.CheckTunnelOwner() != nil {
+				return
+			}
+			utils.Terminal().Section(fmt.Sprintf("Profile %s (%s)", p.Name, p.Interface))
+			if err := disconnectTunnel(); err != nil {
+				reportError(err)
+				return
+			}
+			disconnected++
+		})
+		if err != nil {
+			return failed(cmd, err)
+		}
+		if disconnected == 0 {
+			utils.Terminal().Printf("No active WireGuard configuration found. Already disconnected.")
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(disconnectCmd)
+
+	disconnectCmd.Flags().BoolVar(&disconnectAll, "all", false, "Disconnect the tunnels of every server profile")
+}
+
+// disconnectTunnel stops the tunnel of the profile in use, unless another
+// profile sharing its interface brought it up.
+func disconnectTunnel() error {
+	if err := wiredoor.CheckTunnelOwner(); err != nil {
+		return err
+	}
+	wiredoor.Disconnect()
+	return nil
 }
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -15,7 +16,7 @@ import (
 var enableTtl string
 
 var enableCmd = &cobra.Command{
-	Use:   "enable <type> <ID>",
+	Use:   "enable [type] <name|ID>",
 	Short: "Re-enable a previously disabled Wiredoor service",
 	Long: `Re-enable a previously disabled Wiredoor service.
 
@@ -23,40 +24,61 @@ This command restores public access to a service that was disabled using 'wiredo
 It does not require redefining the configuration — it simply reactivates the route on the Wiredoor gateway.
 
 Arguments:
-  <type>   The type of service to enable: "http" or "tcp"
-  <ID>     The ID of the service to enable (check services ID with 'wiredoor status')
+  [type]        Optional service type: "http" or "tcp". Inferred when omitted.
+  <name|ID>     The name given to 'wiredoor http/tcp <name>', or the service ID
+                (see 'wiredoor ls')
+
+If a name matches both an HTTP and a TCP service, the command fails and lists
+the candidates; add the type to pick one.
 
 Optional flags:
 	--ttl						 Time-to-live duration for the exposure (e.g., "30m", "1h", "2d").
                    Automatically disables the service after the specified duration.
 
 Examples:
+  wiredoor enable my-website
+  wiredoor enable tcp db-access --ttl 1h
   wiredoor enable http 4
-  wiredoor enable tcp 5
 
 Note:
   The service must already exist and be currently disabled.
   If the service is already enabled, this command has no effect.`,
 	Example: `  # Re-enable a public website
-  wiredoor enable http 4
+  wiredoor enable my-website
 
   # Re-enable a TCP service
   wiredoor enable tcp 5`,
-	Args: cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		serviceType := args[0]
-		serviceId := args[1]
-
-		if serviceType != "http" && serviceType != "tcp" {
-			utils.Terminal().Println("Invalid service type. Must be 'http' or 'tcp'.")
-			return
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		serviceTtl, err := wiredoor.NormalizeTTL(enableTtl)
+		if err != nil {
+			return failed(cmd, err)
 		}
-		// utils.Terminal().Printf("Enabling %s service '%s'...\n", serviceType, serviceId)
 
-		utils.Terminal().StartProgress(fmt.Sprintf("Enabling %s service '%s'...\n", strings.ToUpper(serviceType), serviceId))
+		utils.Terminal().StartProgress("Looking up service...")
 		defer utils.Terminal().StopProgress()
 
-		wiredoor.EnableServiceByType(wiredoor.EnableRequest{ServiceType: serviceType, ID: serviceId, Ttl: enableTtl})
+		target, err := resolveServiceArgs(ctx, args)
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().UpdateProgress(fmt.Sprintf("Enabling %s service '%s'...\n", strings.ToUpper(target.Type), target.Name()))
+
+		service, err := wiredoor.EnableServiceByType(ctx, wiredoor.EnableRequest{ServiceType: target.Type, ID: strconv.FormatInt(target.ID(), 10), Ttl: serviceTtl})
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		utils.Terminal().Section("Service Enabled Successfully!")
+
+		utils.Terminal().Render(service, service.Print)
+		return nil
 	},
 }
 
@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+// structuredError is the stderr form of reportError with --output json|yaml.
+type structuredError struct {
+	Level            string                     `json:"level"`
+	Message          string                     `json:"message"`
+	Status           int                        `json:"status,omitempty"`
+	ValidationErrors []wiredoor.ValidationError `json:"validationErrors,omitempty"`
+}
+
+// errReported is returned by commands that already showed why they failed,
+// so that wiredoor only exits with status 1.
+var errReported = errors.New("command failed")
+
+// failed reports err and makes the RunE function of cmd exit with status 1:
+//
+//	if err != nil {
+//		return failed(cmd, err)
+//	}
+func failed(cmd *cobra.Command, err error) error {
+	reportError(err)
+	return exitError(cmd)
+}
+
+// exitError makes the RunE function of cmd exit with status 1 once it
+// printed the failure itself.
+func exitError(cmd *cobra.Command) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return errReported
+}
+
+// reportError prints err to stderr, expanding server side validation
+// errors into one line per rejected field.
+func reportError(err error) {
+	var apiErr *wiredoor.APIError
+
+	if utils.Terminal().Structured() {
+		out := structuredError{Level: "error", Message: err.Error()}
+		if errors.As(err, &apiErr) {
+			out.Status = apiErr.StatusCode
+			out.ValidationErrors = apiErr.ValidationErrors
+		}
+		utils.Terminal().StructuredError(out)
+		return
+	}
+
+	if errors.As(err, &apiErr) && len(apiErr.ValidationErrors) > 0 {
+		message := apiErr.Message
+		if message == "" {
+			message = "Validation failed"
+		}
+		utils.Terminal().Errorf("%s", message)
+		for _, v := range apiErr.ValidationErrors {
+			utils.Terminal().Errorf(" -> %s: %s", v.Field, v.Message)
+		}
+		return
+	}
+
+	utils.Terminal().Errorf("%v", err)
+
+	switch {
+	case wiredoor.IsUnauthorized(err):
+		utils.Terminal().Hint("Your node token may have been revoked. Run 'wiredoor login' or 'wiredoor config' to set a new one.")
+	case wiredoor.IsClientCertificateRejected(err):
+		utils.Terminal().Hint("Set a client certificate accepted by the server with 'wiredoor config --client-cert <file> --client-key <file>'.")
+	case wiredoor.IsCertificateError(err):
+		utils.Terminal().Hint("Set [server] ca_file or pin_sha256 in " + wiredoor.GetConfigLocation() + ", or run 'wiredoor login' to pin the server certificate.")
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	execHttp        []string
+	execTcp         []string
+	execWaitTimeout time.Duration
+	execBackendHost string
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command and expose its services while it runs",
+	Long: `Run a command and expose the services it listens on for as long as it runs.
+
+The command is started first. Once every backend port accepts connections, the
+services are exposed through Wiredoor. The command runs in its own process
+group and gets the terminal, so Ctrl+C reaches it once; SIGTERM received by
+wiredoor is forwarded to it. When the command exits, the services are disabled
+again and wiredoor exits with the command's exit code.
+
+Services that were already enabled before the command started are left enabled.
+
+Service flags (at least one, can be repeated):
+  --http name:domain:port   Expose an HTTP service on domain, forwarded to localhost:port
+  --tcp name:port[/udp]     Expose a TCP (or UDP) service on a port assigned by the gateway
+
+Optional flags:
+  --backendHost             Host the services run on, required on gateway nodes (defaults to "localhost")
+  --wait-timeout            How long to wait for the backend ports to open (default: 1m)`,
+	Example: `  # Expose a dev server while it runs
+  wiredoor exec --http web:dev.example.com:5173 -- npm run dev
+
+  # Preview environment in a CI job
+  wiredoor exec --http preview:pr-42.example.com:8080 --wait-timeout 5m -- ./start-preview.sh
+
+  # Expose an HTTP app and its database
+  wiredoor exec --http app:app.example.com:3000 --tcp db:5432 -- docker compose up
+
+  # On a gateway node, for a command starting a service on another host
+  wiredoor exec --backendHost 10.0.0.100 --http app:app.example.com:8080 -- ./deploy-and-run.sh`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if code := runExec(cmd.Context(), args); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().StringArrayVar(&execHttp, "http", nil, "HTTP service to expose as name:domain:port")
+	execCmd.Flags().StringArrayVar(&execTcp, "tcp", nil, "TCP service to expose as name:port, or name:port/udp")
+	execCmd.Flags().StringVar(&execBackendHost, "backendHost", "", "Host the services run on (used by gateway nodes)")
+	execCmd.Flags().DurationVar(&execWaitTimeout, "wait-timeout", time.Minute, "How long to wait for the backend ports to open")
+	// Flags after the command name belong to the command.
+	execCmd.Flags().SetInterspersed(false)
+}
+
+// runExec runs the command in args and returns the exit code for wiredoor.
+func runExec(ctx context.Context, args []string) int {
+	exposures, err := parseExecServices(execHttp, execTcp, execBackendHost)
+	if err != nil {
+		utils.Terminal().Errorf("%v", err)
+		return 1
+	}
+
+	node, err := wiredoor.GetNode(ctx)
+	if err != nil {
+		reportError(err)
+		return 1
+	}
+	if node.IsGateway && execBackendHost == "" {
+		utils.Terminal().Hint("You must define --backendHost when your node is a gateway")
+		return 1
+	}
+
+	for i := range exposures {
+		e := &exposures[i]
+		e.wasEnabled, err = wasEnabled(ctx, e.service.Type, e.service.Name())
+		if err != nil {
+			reportError(err)
+			return 1
+		}
+	}
+
+	// Registered before the command starts so no signal is missed; while it
+	// is active Ctrl+C no longer kills wiredoor, the command decides.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	child := exec.Command(args[0], args[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	restore, err := startExec(child)
+	if err != nil {
+		utils.Terminal().Errorf("Unable to start %s: %v", args[0], err)
+		return 127
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		err := child.Wait()
+		restore()
+		exited <- err
+	}()
+
+	go func() {
+		for sig := range signals {
+			forwardSignal(child.Process, sig)
+		}
+	}()
+
+	if err := waitForBackends(ctx, exposures, exited); err != nil {
+		var exitErr execExit
+		if errors.As(err, &exitErr) {
+			utils.Terminal().Errorf("%s exited before its services were exposed", args[0])
+			return exitCode(exitErr.err)
+		}
+		if errors.Is(err, context.Canceled) {
+			return exitCode(<-exited)
+		}
+		utils.Terminal().Errorf("%v", err)
+		stopProcess(child.Process, os.Interrupt)
+		<-exited
+		return 1
+	}
+
+	services := []wiredoor.Service{}
+	for i, e := range exposures {
+		service, err := exposeService(ctx, e.service)
+		if err != nil {
+			reportError(err)
+			stopProcess(child.Process, os.Interrupt)
+			<-exited
+			releaseExposures(exposures[:i])
+			return 1
+		}
+		exposures[i].service = service
+		services = append(services, service)
+	}
+
+	utils.Terminal().Section("Services Available")
+	utils.Terminal().Render(services, func() {
+		for _, service := range services {
+			service.Print()
+		}
+	})
+
+	err = <-exited
+	releaseExposures(exposures)
+
+	return exitCode(err)
+}
+
+// execExit reports that the command exited while waiting for its ports.
+type execExit struct {
+	err error
+}
+
+func (e execExit) Error() string {
+	return "command exited"
+}
+
+// waitForBackends probes the backend ports of exposures, like the check
+// made before exposing a service, until all of them accept connections.
+func waitForBackends(ctx context.Context, exposures []exposure, exited <-chan error) error {
+	deadline := time.After(execWaitTimeout)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for _, e := range exposures {
+		proto := "tcp"
+		if e.udp {
+			proto = "udp"
+		}
+
+		utils.Terminal().Printf("Waiting for %s:%d to accept connections...", e.backendHost, e.backendPort)
+		for utils.ProbeBackend(e.backendHost, e.backendPort, proto, time.Second) != nil {
+			select {
+			case err := <-exited:
+				return execExit{err: err}
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline:
+				return fmt.Errorf("port %d did not accept connections within %s", e.backendPort, execWaitTimeout)
+			case <-ticker.C:
+			}
+		}
+	}
+
+	return nil
+}
+
+// exposeService creates or updates the service described by service.
+func exposeService(ctx context.Context, service wiredoor.Service) (wiredoor.Service, error) {
+	if service.Type == "http" {
+		exposed, err := wiredoor.ExposeHTTP(ctx, service.Http.HttpServiceParams)
+		return wiredoor.Service{Type: "http", Http: &exposed}, err
+	}
+
+	exposed, err := wiredoor.ExposeTCP(ctx, service.Tcp.TcpServiceParams)
+	return wiredoor.Service{Type: "tcp", Tcp: &exposed}, err
+}
+
+// parseExecServices turns the --http and --tcp values into exposures of
+// backends running on backendHost.
+func parseExecServices(httpSpecs []string, tcpSpecs []string, backendHost string) ([]exposure, error) {
+	if len(httpSpecs) == 0 && len(tcpSpecs) == 0 {
+		return nil, fmt.Errorf("at least one --http or --tcp service is required")
+	}
+
+	exposures := []exposure{}
+
+	for _, spec := range httpSpecs {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --http %q: expected name:domain:port", spec)
+		}
+		port, err := parseExecPort(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --http %q: %w", spec, err)
+		}
+
+		exposures = append(exposures, exposure{
+			service: wiredoor.Service{Type: "http", Http: &wiredoor.HttpService{HttpServiceParams: wiredoor.HttpServiceParams{
+				Name:         parts[0],
+				Domain:       parts[1],
+				BackendHost:  backendHost,
+				BackendPort:  port,
+				BackendProto: "http",
+				PathLocation: "/",
+			}}},
+			backendHost: backendTarget(backendHost),
+			backendPort: port,
+		})
+	}
+
+	for _, spec := range tcpSpecs {
+		proto := "tcp"
+		value := spec
+		if base, suffix, found := strings.Cut(spec, "/"); found {
+			value, proto = base, strings.ToLower(suffix)
+		}
+		if proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("invalid --tcp %q: protocol must be tcp or udp", spec)
+		}
+
+		name, portValue, found := strings.Cut(value, ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --tcp %q: expected name:port or name:port/udp", spec)
+		}
+		port, err := parseExecPort(portValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tcp %q: %w", spec, err)
+		}
+
+		exposures = append(exposures, exposure{
+			service: wiredoor.Service{Type: "tcp", Tcp: &wiredoor.TcpService{TcpServiceParams: wiredoor.TcpServiceParams{
+				Name:        name,
+				Proto:       proto,
+				BackendHost: backendHost,
+				BackendPort: port,
+			}}},
+			backendHost: backendTarget(backendHost),
+			backendPort: port,
+			udp:         proto == "udp",
+		})
+	}
+
+	return exposures, nil
+}
+
+func parseExecPort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", value)
+	}
+	return port, nil
+}
+
+// exitCode maps the result of the command to wiredoor's exit code, using
+// the shell convention of 128+N for a command killed by signal N.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// startExec starts child in its own process group, so it only receives the
+// signals wiredoor forwards. When wiredoor runs in the foreground of a
+// terminal the group is given the terminal, like a shell does: Ctrl+C and
+// keyboard input then reach the command once, and restore gives the
+// terminal back to wiredoor after the command exits.
+func startExec(child *exec.Cmd) (restore func(), err error) {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+
+	tty := int(os.Stdin.Fd())
+	foreground := false
+	if pgrp, err := unix.IoctlGetInt(tty, unix.TIOCGPGRP); err == nil && pgrp == syscall.Getpgrp() {
+		attr.Foreground = true
+		attr.Ctty = tty
+		foreground = true
+	}
+
+	child.SysProcAttr = attr
+	if err := child.Start(); err != nil {
+		return nil, err
+	}
+
+	if !foreground {
+		return func() {}, nil
+	}
+	return func() {
+		// Taking the terminal back from a background group raises SIGTTOU.
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, syscall.Getpgrp())
+	}, nil
+}
+
+// stopProcess sends sig to the process group of process, so the children
+// it started (e.g. by npm or docker compose) get it too.
+func stopProcess(process *os.Process, sig os.Signal) {
+	s, ok := sig.(syscall.Signal)
+	if !ok || syscall.Kill(-process.Pid, s) != nil {
+		_ = process.Kill()
+	}
+}
+
+// forwardSignal passes a signal received by wiredoor on to the command.
+func forwardSignal(process *os.Process, sig os.Signal) {
+	stopProcess(process, sig)
+}
@@ -0,0 +1,38 @@
+//go:build windows
+// +build windows
+
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startExec starts child attached to wiredoor's console.
+func startExec(child *exec.Cmd) (restore func(), err error) {
+	if err := child.Start(); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+// stopProcess sends sig to process. Windows cannot deliver os.Interrupt to
+// another process, so it is killed instead.
+func stopProcess(process *os.Process, sig os.Signal) {
+	if err := process.Signal(sig); err != nil {
+		_ = process.Kill()
+	}
+}
+
+// forwardSignal passes a signal received by wiredoor on to the command.
+// Ctrl+C is not forwarded: the console already sent it to every process
+// attached to it, and forwarding it would kill the command.
+func forwardSignal(process *os.Process, sig os.Signal) {
+	if sig == os.Interrupt {
+		return
+	}
+	stopProcess(process, sig)
+}
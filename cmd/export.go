@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	exportFormat  string
+	exportGateway bool
+	exportPorts   bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print the node's services as a manifest for 'wiredoor apply'",
+	Long: `Print the HTTP and TCP services of this node as a manifest.
+
+The manifest includes every setting needed to recreate the services on another
+node with 'wiredoor apply': domain, path, backend host, port and protocol, SSL,
+allow/block lists, TTL and whether the service is enabled.
+
+The public ports of TCP services are assigned by the server and left out, so
+the target server picks free ones. Use --ports to keep them.
+
+Optional flags:
+  --format     Manifest format: yaml or json (default: yaml)
+  --gateway    Include the gateway networks (gateway nodes only)
+  --ports      Include the public ports of TCP services`,
+	Example: `  # Back up the node's services
+  wiredoor export > services.yaml
+
+  # Clone them on another node
+  wiredoor apply -f services.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		node, err := wiredoor.GetNode(ctx)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		manifest := wiredoor.ManifestFromNode(node, wiredoor.ExportOptions{Gateway: exportGateway, Ports: exportPorts})
+
+		// --output json|yaml picks the format unless --format is given.
+		if !cmd.Flags().Changed("format") && utils.Terminal().Structured() {
+			exportFormat = output
+		}
+
+		if err := manifest.Encode(cmd.OutOrStdout(), exportFormat); err != nil {
+			return failed(cmd, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVar(&exportFormat, "format", "yaml", "Manifest format: yaml or json")
+	exportCmd.Flags().BoolVar(&exportGateway, "gateway", false, "Include gateway networks")
+	exportCmd.Flags().BoolVar(&exportPorts, "ports", false, "Include the public ports of TCP services")
+}
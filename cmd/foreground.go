@@ -0,0 +1,170 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+// cleanupTimeout bounds the API calls made after the command context has
+// been cancelled by a signal.
+const cleanupTimeout = 15 * time.Second
+
+// exposure is a service exposed for the lifetime of the command.
+type exposure struct {
+	service wiredoor.Service
+
+	// wasEnabled is true when the service already existed and was enabled
+	// before this command exposed it; it is then left enabled on exit.
+	wasEnabled bool
+
+	// restore, when set, puts back the settings the service had before
+	// this command changed them. It runs on exit before anything else, and
+	// returns wiredoor.ErrExpired when it disabled a service whose previous
+	// ttl ran out in the meantime.
+	restore func(ctx context.Context) error
+
+	backendHost string
+	backendPort int
+	udp         bool // UDP backends are not probed
+}
+
+// backendTarget returns the host the backend is reached on: the gateway
+// backend host, or localhost.
+func backendTarget(backendHost string) string {
+	if backendHost == "" {
+		return "localhost"
+	}
+	return backendHost
+}
+
+// wasEnabled reports whether a service named name of serviceType is
+// currently enabled on the node.
+func wasEnabled(ctx context.Context, serviceType string, name string) (bool, error) {
+	svc, err := existingService(ctx, serviceType, name)
+	if err != nil || svc == nil {
+		return false, err
+	}
+	return svc.Enabled(), nil
+}
+
+// existingService returns the service named name of serviceType, or nil
+// when there is none.
+func existingService(ctx context.Context, serviceType string, name string) (*wiredoor.Service, error) {
+	services, err := wiredoor.ListServices(ctx, serviceType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, svc := range services {
+		if svc.Name() == name {
+			return &svc, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// holdExposures keeps the command running with a live status line until ctx
+// is cancelled (Ctrl+C or SIGTERM), then restores the previous state of
+// the exposed services.
+func holdExposures(ctx context.Context, exposures []exposure) {
+	utils.Terminal().Println("")
+	utils.Terminal().Println("Press Ctrl+C to stop exposing and disable the service.")
+
+	start := time.Now()
+	backendUp := make([]bool, len(exposures))
+
+	probe := func() {
+		for i, e := range exposures {
+			if !e.udp {
+				backendUp[i] = utils.DialPort(e.backendHost, e.backendPort, time.Second) == nil
+			}
+		}
+	}
+	status := func() string {
+		line := fmt.Sprintf("Exposed for %s", time.Since(start).Round(time.Second))
+		for i, e := range exposures {
+			line += fmt.Sprintf(" | %s -> %s:%d", e.service.Name(), e.backendHost, e.backendPort)
+			switch {
+			case e.udp:
+			case backendUp[i]:
+				line += " up"
+			default:
+				line += " DOWN"
+			}
+		}
+		return line
+	}
+
+	probe()
+	utils.Terminal().StartProgress(status())
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for tick := 1; ; tick++ {
+		select {
+		case <-ctx.Done():
+			utils.Terminal().FinalizeProgress()
+			releaseExposures(exposures)
+			return
+		case <-ticker.C:
+			if tick%5 == 0 {
+				probe()
+			}
+			utils.Terminal().UpdateProgress(status())
+		}
+	}
+}
+
+// releaseExposures restores the services that have a restore function and
+// disables the ones that were not enabled before. It runs after the command
+// context is done, so it uses its own.
+func releaseExposures(exposures []exposure) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	for _, e := range exposures {
+		name := e.service.Name()
+
+		if e.restore != nil {
+			err := e.restore(ctx)
+			if errors.Is(err, wiredoor.ErrExpired) {
+				utils.Terminal().Printf("Service '%s' expired while it was exposed, disabled.", name)
+				continue
+			}
+			if err != nil {
+				utils.Terminal().Errorf("Unable to restore service '%s': %v", name, err)
+				utils.Terminal().Hint(fmt.Sprintf("Run 'wiredoor %s %s' with its previous settings to restore it.", e.service.Type, name))
+				continue
+			}
+			if e.wasEnabled {
+				utils.Terminal().Printf("Service '%s' restored.", name)
+				continue
+			}
+		}
+
+		if e.wasEnabled {
+			utils.Terminal().Printf("Service '%s' was enabled before, leaving it enabled.", name)
+			continue
+		}
+
+		_, err := wiredoor.DisableServiceByType(ctx, e.service.Type, strconv.FormatInt(e.service.ID(), 10))
+		if err != nil {
+			utils.Terminal().Errorf("Unable to disable service '%s': %v", name, err)
+			utils.Terminal().Hint(fmt.Sprintf("Run 'wiredoor disable %s %s' to disable it.", e.service.Type, name))
+			continue
+		}
+
+		utils.Terminal().Printf("Service '%s' disabled.", name)
+	}
+}
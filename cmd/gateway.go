@@ -38,14 +38,15 @@ Note:
   This command must be run from a registered gateway node with an active connection.`,
 	Example: `  # Update the gateway subnet to match a Kubernetes service network
   wiredoor gateway --subnet=10.42.0.0/16`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
 		if runtime.GOOS == "windows" {
 			utils.Terminal().Println("unsupported OS")
-			return
+			return exitError(cmd)
 		}
 		if gatewaySubnet == "" {
-			cmd.Help()
-			return
+			return cmd.Help()
 		}
 
 		if gatewayInterface == "" {
@@ -53,15 +54,24 @@ Note:
 		}
 
 		if _, _, err := net.ParseCIDR(gatewaySubnet); err != nil {
-			utils.Terminal().Errorf("Invalid subnet format: %s\n", gatewaySubnet)
-			return
+			return failed(cmd, fmt.Errorf("invalid subnet format: %s", gatewaySubnet))
 		}
 
 		// utils.Terminal().Printf("Updating gateway subnet to '%s' using interface '%s'...\n", gatewaySubnet, gatewayInterface)
 		utils.Terminal().StartProgress(fmt.Sprintf("Updating gateway subnet to '%s' using interface '%s'...\n", gatewaySubnet, gatewayInterface))
 		defer utils.Terminal().StopProgress()
 
-		wiredoor.UpdateGatewaySubnet(wiredoor.GatewayNetwork{Interface: gatewayInterface, Subnet: gatewaySubnet})
+		node, err := wiredoor.UpdateGatewaySubnet(ctx, wiredoor.GatewayNetwork{Interface: gatewayInterface, Subnet: gatewaySubnet})
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		utils.Terminal().Render(node, func() {
+			utils.Terminal().Section("Subnet successfully updated to: " + node.GatewayNetwork)
+		})
+		return nil
 	},
 }
 
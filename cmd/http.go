@@ -4,9 +4,14 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"strconv"
+
 	"github.com/spf13/cobra"
 	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/wiredoor"
+	"github.com/wiredoor/wiredoor-cli/wiredoor/inspect"
 )
 
 var (
@@ -18,6 +23,10 @@ var (
 	allowList   []string
 	blockList   []string
 	ttl         string
+	foreground  bool
+	skipCheck   bool
+	inspectUI   bool
+	inspectAt   string
 )
 
 var httpCmd = &cobra.Command{
@@ -44,6 +53,16 @@ Optional flags:
   --block          Comma-separated list of blocked IP addresses or CIDRs (access control)
 	--ttl						 Time-to-live duration for the exposure (e.g., "30m", "1h", "2d").
                    Automatically disables the service after the specified duration.
+  --foreground     Keep running and disable the service on Ctrl+C or SIGTERM.
+                   A service that was already enabled is left enabled.
+  --skip-check     Expose the service even if the backend does not accept connections.
+  --inspect        Record the requests sent to the service and browse them at
+                   http://127.0.0.1:4040 or with 'wiredoor inspect'. Implies --foreground.
+  --inspect-addr   Address of the inspector UI (default: 127.0.0.1:4040)
+
+Backend check:
+  Before the service is exposed, the backend (localhost:port, or backendHost:port)
+  must accept connections. With --proto https a TLS handshake is also made.
 
 Example scenario:
   You have a local service running on http://localhost:3000 and want to expose it as:
@@ -55,6 +74,13 @@ Example scenario:
   You can also restrict access to specific IPs:
     wiredoor http my-website --domain website.com --port 3000 --allow 192.168.1.0/24
 
+Request inspector:
+  With --inspect, a local proxy is placed between the tunnel and your service
+  and the service is pointed at it while the command runs. Request and response
+  headers and bodies (up to 64 KiB each) of the last 100 requests are kept in
+  memory; 'wiredoor replay <request-id>' sends one to your service again.
+  On exit the service gets its previous settings back. Not available on gateway nodes.
+
 Certificates:
   - If the domain is public and resolves to the Wiredoor gateway, a valid certificate is obtained via Let's Encrypt.
   - If the domain is private or doesn't resolve, a self-signed certificate will be used.`,
@@ -74,30 +100,121 @@ Certificates:
   wiredoor http my-website --domain website.com --port 3000 --block 203.0.113.42
 	
 	# Expose service temporarily for 1 hour
-  wiredoor http my-website --domain website.com --port 3000 --ttl 1h`,
+  wiredoor http my-website --domain website.com --port 3000 --ttl 1h
+
+  # Expose service until Ctrl+C
+  wiredoor http demo --domain demo.website.com --port 3000 --foreground
+
+  # Debug webhooks sent to a local service
+  wiredoor http hooks --domain hooks.website.com --port 8080 --inspect`,
 	Args: cobra.ExactArgs(1), // require "name"
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
 		name := args[0]
 
-		node := wiredoor.GetNode()
+		serviceTtl, err := wiredoor.NormalizeTTL(ttl)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		node, err := wiredoor.GetNode(ctx)
+
+		if err != nil {
+			return failed(cmd, err)
+		}
 
 		if node.IsGateway && backendHost == "" {
 			utils.Terminal().Hint("You must define --backendHost when your node is a gateway")
-			return
+			return exitError(cmd)
+		}
+
+		if inspectUI && node.IsGateway {
+			return failed(cmd, errors.New("--inspect is not available on gateway nodes"))
+		}
+
+		if !skipCheck && !checkBackend(backendHost, port, proto) {
+			return exitError(cmd)
 		}
+
 		utils.Terminal().StartProgress("Configuring HTTP service...")
 		defer utils.Terminal().StopProgress()
-		wiredoor.ExposeHTTP(wiredoor.HttpServiceParams{
+
+		var previous *wiredoor.Service
+		if foreground || inspectUI {
+			previous, err = existingService(ctx, "http", name)
+			if err != nil {
+				return failed(cmd, err)
+			}
+		}
+
+		// With --inspect the service points at the inspector proxy, which
+		// forwards to the backend.
+		servicePort, serviceProto := port, proto
+		var session *inspectSession
+		if inspectUI {
+			session, err = startInspector(proto, port, inspectAt)
+			if err != nil {
+				return failed(cmd, err)
+			}
+			defer session.Close()
+			servicePort, serviceProto = session.port, "http"
+		}
+
+		service, err := wiredoor.ExposeHTTP(ctx, wiredoor.HttpServiceParams{
 			Name:         name,
 			Domain:       domain,
-			BackendPort:  port,
-			BackendProto: proto,
+			BackendPort:  servicePort,
+			BackendProto: serviceProto,
 			BackendHost:  backendHost,
 			PathLocation: path,
 			AllowedIps:   allowList,
 			BlockedIps:   blockList,
-			Ttl:          ttl,
-		}, node)
+			Ttl:          serviceTtl,
+		})
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		utils.Terminal().Section("HTTP Service Available")
+
+		utils.Terminal().Render(service, func() {
+			wiredoor.PrintHttpServices([]wiredoor.HttpService{service}, node.IsGateway)
+		})
+
+		if inspectUI {
+			utils.Terminal().Println("")
+			utils.Terminal().Printf("Inspect requests at http://%s or with 'wiredoor inspect'.", session.uiAddr)
+		}
+
+		if foreground || inspectUI {
+			e := exposure{
+				service:     wiredoor.Service{Type: "http", Http: &service},
+				wasEnabled:  previous != nil && previous.Enabled(),
+				backendHost: backendTarget(backendHost),
+				backendPort: port,
+			}
+			if inspectUI && previous != nil {
+				e.restore = func(ctx context.Context) error {
+					params := previous.Http.HttpServiceParams
+					ttl, err := wiredoor.RemainingTTL(previous.ExpiresAt())
+					if errors.Is(err, wiredoor.ErrExpired) {
+						_, err = wiredoor.DisableServiceByType(ctx, "http", strconv.FormatInt(service.ID, 10))
+						if err != nil {
+							return err
+						}
+						return wiredoor.ErrExpired
+					}
+					params.Ttl = ttl
+					_, err = wiredoor.ExposeHTTP(ctx, params)
+					return err
+				}
+			}
+			holdExposures(ctx, []exposure{e})
+		}
+		return nil
 	},
 }
 
@@ -112,4 +229,8 @@ func init() {
 	httpCmd.Flags().StringSliceVar(&allowList, "allow", nil, "List of allowed IPs or CIDRs")
 	httpCmd.Flags().StringSliceVar(&blockList, "block", nil, "List of blocked IPs or CIDRs")
 	httpCmd.Flags().StringVar(&ttl, "ttl", "", "Time-to-live duration for the exposure (e.g., 30m, 1h, 2d)")
+	httpCmd.Flags().BoolVar(&foreground, "foreground", false, "Keep running and disable the service on exit")
+	httpCmd.Flags().BoolVar(&inspectUI, "inspect", false, "Record requests through a local proxy and serve an inspector UI")
+	httpCmd.Flags().StringVar(&inspectAt, "inspect-addr", inspect.DefaultUIAddress, "Address of the inspector UI (used with --inspect)")
+	httpCmd.Flags().BoolVar(&skipCheck, "skip-check", false, "Do not check that the backend accepts connections")
 }
@@ -0,0 +1,203 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+	"github.com/wiredoor/wiredoor-cli/wiredoor/inspect"
+)
+
+var inspectAddr string
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [request-id]",
+	Short: "Browse the requests recorded by 'wiredoor http --inspect'",
+	Long: `Browse the requests recorded by a running 'wiredoor http --inspect'.
+
+Without arguments the recorded requests are listed, newest first. With a
+request ID the request and response headers and bodies are printed.
+
+The same data is available in a browser at the inspector address
+(default: http://127.0.0.1:4040).
+
+Optional flags:
+  --addr       Address of the inspector UI (default: 127.0.0.1:4040)`,
+	Example: `  # List the recorded requests
+  wiredoor inspect
+
+  # Show request 12
+  wiredoor inspect 12
+
+  # Send it to the backend again
+  wiredoor replay 12`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client := inspect.NewClient(inspectAddr)
+
+		if len(args) == 0 {
+			exchanges, err := client.List(ctx)
+			if err != nil {
+				return failed(cmd, err)
+			}
+			utils.Terminal().Render(exchanges, func() {
+				printExchanges(exchanges)
+			})
+			return nil
+		}
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return failed(cmd, fmt.Errorf("invalid request ID %q", args[0]))
+		}
+
+		ex, err := client.Get(ctx, id)
+		if err != nil {
+			return failed(cmd, err)
+		}
+		utils.Terminal().Render(ex, func() {
+			printExchange(ex)
+		})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().StringVar(&inspectAddr, "addr", inspect.DefaultUIAddress, "Address of the inspector UI")
+}
+
+// inspectSession is the inspector proxy and UI run by 'wiredoor http --inspect'.
+type inspectSession struct {
+	port    int // port of the proxy on the tunnel address
+	uiAddr  string
+	servers []*http.Server
+}
+
+// startInspector starts a proxy to the backend on the tunnel address, for
+// the gateway to reach, and the inspector UI on uiAddr.
+func startInspector(backendProto string, backendPort int, uiAddr string) (*inspectSession, error) {
+	tunnelIP := wiredoor.TunnelIP()
+	if tunnelIP == "" {
+		return nil, fmt.Errorf("the Wiredoor tunnel is not active; run 'wiredoor connect' first")
+	}
+
+	target, err := url.Parse(fmt.Sprintf("%s://localhost:%d", strings.ToLower(backendProto), backendPort))
+	if err != nil {
+		return nil, err
+	}
+	inspector := inspect.New(inspect.Options{Target: target})
+
+	proxyListener, err := net.Listen("tcp", net.JoinHostPort(tunnelIP, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to start inspector proxy: %w", err)
+	}
+	uiListener, err := net.Listen("tcp", uiAddr)
+	if err != nil {
+		proxyListener.Close()
+		return nil, fmt.Errorf("unable to start inspector UI on %s: %w", uiAddr, err)
+	}
+
+	session := &inspectSession{
+		port:   proxyListener.Addr().(*net.TCPAddr).Port,
+		uiAddr: uiListener.Addr().String(),
+		servers: []*http.Server{
+			{Handler: inspector, ReadHeaderTimeout: 10 * time.Second},
+			{Handler: inspector.Handler(), ReadHeaderTimeout: 10 * time.Second},
+		},
+	}
+	go session.servers[0].Serve(proxyListener)
+	go session.servers[1].Serve(uiListener)
+
+	return session, nil
+}
+
+func (s *inspectSession) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, server := range s.servers {
+		_ = server.Shutdown(ctx)
+	}
+}
+
+func printExchanges(exchanges []inspect.Exchange) {
+	if len(exchanges) == 0 {
+		utils.Terminal().Println("No requests recorded yet.")
+		return
+	}
+
+	rows := make([][]string, 0, len(exchanges))
+	for _, ex := range exchanges {
+		id := strconv.FormatInt(ex.ID, 10)
+		if ex.ReplayOf != 0 {
+			id += fmt.Sprintf(" (replay of %d)", ex.ReplayOf)
+		}
+		rows = append(rows, []string{
+			id,
+			ex.Time.Local().Format("15:04:05"),
+			ex.Method,
+			ex.URL,
+			exchangeStatus(ex),
+			fmt.Sprintf("%d ms", ex.Duration),
+		})
+	}
+
+	utils.Terminal().Table([]string{"ID", "TIME", "METHOD", "URL", "STATUS", "DURATION"}, rows)
+}
+
+func printExchange(ex inspect.Exchange) {
+	utils.Terminal().Section(fmt.Sprintf("%s %s", ex.Method, ex.URL))
+	utils.Terminal().KV("Time", ex.Time.Local().Format("2006-01-02 15:04:05"))
+	utils.Terminal().KV("Host", ex.Host)
+	utils.Terminal().KV("Duration", fmt.Sprintf("%d ms", ex.Duration))
+	if ex.ReplayOf != 0 {
+		utils.Terminal().KV("Replay of", ex.ReplayOf)
+	}
+
+	utils.Terminal().Section("Request:")
+	printMessage(ex.RequestHeaders, ex.RequestBody, ex.RequestBodyTruncated)
+
+	utils.Terminal().Section("Response:")
+	if ex.Error != "" {
+		utils.Terminal().KV("Error", ex.Error)
+		return
+	}
+	utils.Terminal().KV("Status", ex.Status)
+	printMessage(ex.ResponseHeaders, ex.ResponseBody, ex.ResponseBodyTruncated)
+}
+
+func printMessage(headers http.Header, body []byte, truncated bool) {
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		utils.Terminal().KV(name, strings.Join(headers[name], ", "))
+	}
+	if len(body) > 0 {
+		utils.Terminal().Println("")
+		utils.Terminal().Println(inspect.BodyText(body))
+	}
+	if truncated {
+		utils.Terminal().Println("(body truncated)")
+	}
+}
+
+func exchangeStatus(ex inspect.Exchange) string {
+	if ex.Error != "" {
+		return "error"
+	}
+	return strconv.Itoa(ex.Status)
+}
@@ -41,6 +41,8 @@ Prompts will guide you through the registration and configuration process.`,
   # Connect to a public Wiredoor server
   wiredoor login --url https://wiredoor.example.com`,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		url, _ := cmd.Flags().GetString("url")
 
 		if url == "" && !wiredoor.IsServerConfigSet() {
@@ -48,6 +50,10 @@ Prompts will guide you through the registration and configuration process.`,
 			return
 		}
 
+		if url == "" {
+			url = wiredoor.GetServerConfig().Url
+		}
+
 		if wiredoor.IsServerConfigSet() {
 			doContinue := false
 
@@ -61,6 +67,11 @@ Prompts will guide you through the registration and configuration process.`,
 			}
 		}
 
+		if err := confirmServerCertificate(url); err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+
 		var username, password, nodeName, subnet, iface string
 		var isGateway, allowInternet bool
 
@@ -76,10 +87,10 @@ Prompts will guide you through the registration and configuration process.`,
 			Message: "Password:",
 		}, &password, survey.WithValidator(survey.Required))
 
-		token, err := wiredoor.AdminLogin(url, username, password)
+		token, err := wiredoor.AdminLogin(ctx, url, username, password)
 
 		if err != nil {
-			utils.Terminal().Errorf("%v", err)
+			reportError(err)
 			os.Exit(1)
 		}
 
@@ -117,7 +128,7 @@ Prompts will guide you through the registration and configuration process.`,
 			Default: false,
 		}, &allowInternet)
 
-		node, err := wiredoor.ConfigureNode(url, token, wiredoor.NodeParams{
+		node, err := wiredoor.ConfigureNode(ctx, url, token, wiredoor.NodeParams{
 			Name:            nodeName,
 			IsGateway:       isGateway,
 			GatewayNetworks: gatewayNetworks,
@@ -125,17 +136,21 @@ Prompts will guide you through the registration and configuration process.`,
 		})
 
 		if err != nil {
-			utils.Terminal().Errorf("Error: %v", err)
+			reportError(err)
 			os.Exit(1)
 		}
 
 		utils.Terminal().Printf("Node %s registered successfully!\n", node.Name)
 
-		wiredoor.Connect(wiredoor.ConnectionConfig{})
+		if err := wiredoor.Connect(ctx, wiredoor.ConnectionConfig{}); err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
+	loginCmd.Annotations = createsProfile
 	rootCmd.AddCommand(loginCmd)
 	loginCmd.Flags().String("url", "", "URL Domain or Server IP of Wiredoor instance")
 }
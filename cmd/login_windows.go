@@ -45,6 +45,8 @@ Prompts will guide you through the registration and configuration process.`,
   # Connect to a public Wiredoor server
   wiredoor login --url https://wiredoor.example.com`,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		url, _ := cmd.Flags().GetString("url")
 
 		if url == "" && !wiredoor.IsServerConfigSet() {
@@ -52,6 +54,10 @@ Prompts will guide you through the registration and configuration process.`,
 			return
 		}
 
+		if url == "" {
+			url = wiredoor.GetServerConfig().Url
+		}
+
 		if wiredoor.IsServerConfigSet() {
 			doContinue := false
 
@@ -65,6 +71,11 @@ Prompts will guide you through the registration and configuration process.`,
 			}
 		}
 
+		if err := confirmServerCertificate(url); err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+
 		var username, password, nodeName, subnet, iface string
 		var isGateway, allowInternet bool
 
@@ -80,10 +91,10 @@ Prompts will guide you through the registration and configuration process.`,
 			Message: "Password:",
 		}, &password, survey.WithValidator(survey.Required))
 
-		token, err := wiredoor.AdminLogin(url, username, password)
+		token, err := wiredoor.AdminLogin(ctx, url, username, password)
 
 		if err != nil {
-			utils.Terminal().Errorf("%v", err)
+			reportError(err)
 			os.Exit(1)
 		}
 
@@ -121,7 +132,7 @@ Prompts will guide you through the registration and configuration process.`,
 			Default: false,
 		}, &allowInternet)
 
-		node, err := wiredoor.ConfigureNode(url, token, wiredoor.NodeParams{
+		node, err := wiredoor.ConfigureNode(ctx, url, token, wiredoor.NodeParams{
 			Name:            nodeName,
 			IsGateway:       isGateway,
 			GatewayNetworks: gatewayNetworks,
@@ -129,7 +140,7 @@ Prompts will guide you through the registration and configuration process.`,
 		})
 
 		if err != nil {
-			utils.Terminal().Errorf("%v", err)
+			reportError(err)
 			os.Exit(1)
 		}
 
@@ -164,7 +175,7 @@ Prompts will guide you through the registration and configuration process.`,
 				if response, ok := jsonResponse["response"].(string); ok {
 					switch response {
 					case "ok":
-						wiredoor.Status()
+						wiredoor.Status(ctx)
 						os.Exit(0)
 					default:
 						utils.Terminal().Warnf("Unhandled service reposnse: %v", response)
@@ -191,6 +202,7 @@ Prompts will guide you through the registration and configuration process.`,
 }
 
 func init() {
+	loginCmd.Annotations = createsProfile
 	rootCmd.AddCommand(loginCmd)
 
 	loginCmd.Flags().String("url", "", "URL Domain or Server IP of Wiredoor instance")
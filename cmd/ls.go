@@ -0,0 +1,99 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+var (
+	lsName    string
+	lsDomain  string
+	lsState   string
+	lsProto   string
+	lsSort    string
+	lsColumns string
+)
+
+var lsCmd = &cobra.Command{
+	Use:     "ls [http|tcp]",
+	Aliases: []string{"list"},
+	Short:   "List the services exposed by this node",
+	Long: `List the HTTP and TCP services exposed by this node.
+
+Only the Wiredoor API is queried, so this works even while the local tunnel
+is down. Without a type argument both HTTP and TCP services are listed.
+
+Optional flags:
+  --name      Filter by service name (glob, e.g. "api-*")
+  --domain    Filter by domain (glob, e.g. "*.example.com")
+  --state     Filter by state: enabled or disabled
+  --proto     Filter by protocol: http, https (HTTP backends), tcp or udp
+  --sort      Sort by column, prefix with "-" for descending (default: id)
+  --columns   Comma separated columns to show:
+              id, type, state, name, domain, proto, public, target, ttl, expires
+
+Examples:
+  wiredoor ls
+  wiredoor ls http --state enabled --sort name
+  wiredoor ls --name "api-*" --columns name,public,expires
+  wiredoor ls tcp --proto udp --sort -expires`,
+	Example: `  # Enabled HTTP services sorted by name
+  wiredoor ls http --state enabled --sort name
+
+  # Services expiring soonest first
+  wiredoor ls --sort expires --columns type,name,expires`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"http", "tcp"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		serviceType := ""
+		if len(args) > 0 {
+			serviceType = args[0]
+		}
+
+		columns, err := wiredoor.ParseServiceColumns(lsColumns)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		services, err := wiredoor.ListServices(ctx, serviceType)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		services, err = wiredoor.FilterServices(services, wiredoor.ServiceFilter{
+			Name:   lsName,
+			Domain: lsDomain,
+			State:  lsState,
+			Proto:  lsProto,
+		})
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		if err := wiredoor.SortServices(services, lsSort); err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().Render(services, func() {
+			wiredoor.PrintServiceList(services, columns)
+		})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+
+	lsCmd.Flags().StringVar(&lsName, "name", "", "Filter by service name (glob)")
+	lsCmd.Flags().StringVar(&lsDomain, "domain", "", "Filter by domain (glob)")
+	lsCmd.Flags().StringVar(&lsState, "state", "", "Filter by state: enabled or disabled")
+	lsCmd.Flags().StringVar(&lsProto, "proto", "", "Filter by protocol: http, https, tcp or udp")
+	lsCmd.Flags().StringVar(&lsSort, "sort", "id", "Sort by column, prefix with '-' for descending")
+	lsCmd.Flags().StringVar(&lsColumns, "columns", "", "Comma separated columns to show (default: id,type,state,name,public,target,expires)")
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor/fake"
+)
+
+var mockListen, mockNodeToken, mockAdminUser, mockAdminPassword string
+
+var mockServerCmd = &cobra.Command{
+	Use:    "mock-server",
+	Short:  "Run an in-memory Wiredoor server for offline testing",
+	Hidden: true,
+	Long: `Run an in-memory Wiredoor server for offline testing.
+
+The server implements the parts of the Wiredoor API used by this CLI and keeps
+all state in memory. A node is pre-registered with the token given by --token,
+so the CLI can be pointed at it right away:
+
+  wiredoor mock-server --listen 127.0.0.1:8080 &
+  wiredoor config --url http://127.0.0.1:8080 --token fake-node-token
+  wiredoor http web --domain app.example.com --port 3000
+
+The server stops on Ctrl+C or SIGTERM.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		listener, err := net.Listen("tcp", mockListen)
+		if err != nil {
+			return failed(cmd, fmt.Errorf("Unable to listen on %s: %v", mockListen, err))
+		}
+
+		server := &http.Server{
+			Handler: fake.NewServer(fake.Options{
+				AdminUser:     mockAdminUser,
+				AdminPassword: mockAdminPassword,
+				NodeToken:     mockNodeToken,
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		utils.Terminal().Printf("Mock Wiredoor server listening on http://%s\n", listener.Addr())
+
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return failed(cmd, fmt.Errorf("mock server failed: %w", err))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+	mockServerCmd.Flags().StringVar(&mockListen, "listen", "127.0.0.1:8080", "Address to listen on")
+	mockServerCmd.Flags().StringVar(&mockNodeToken, "token", fake.DefaultNodeToken, "Token of the pre-registered node")
+	mockServerCmd.Flags().StringVar(&mockAdminUser, "admin-user", fake.DefaultAdminUser, "Admin username accepted by 'wiredoor login'")
+	mockServerCmd.Flags().StringVar(&mockAdminPassword, "admin-password", fake.DefaultAdminPassword, "Admin password accepted by 'wiredoor login'")
+}
@@ -0,0 +1,32 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+)
+
+// backendCheckTimeout bounds each backend reachability probe.
+const backendCheckTimeout = 3 * time.Second
+
+// checkBackend probes the backend before it is exposed, so an unreachable
+// service is reported now rather than as 502s later. It reports false when
+// the command should stop.
+func checkBackend(host string, port int, proto string) bool {
+	target := backendTarget(host)
+	proto = strings.ToLower(proto)
+
+	err := utils.ProbeBackend(target, port, proto, backendCheckTimeout)
+	if err == nil {
+		return true
+	}
+
+	utils.Terminal().Errorf("Backend %s://%s:%d is not reachable: %v", proto, target, port, err)
+	utils.Terminal().Hint(fmt.Sprintf("Start the service on port %d first, or use --skip-check to expose it anyway.", port))
+	return false
+}
@@ -8,9 +8,10 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
-	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/wiredoor"
 )
 
@@ -43,7 +44,9 @@ Examples:
 `,
 	Example: `  # Regenerate keys and token for this node
   wiredoor regenerate`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
 		if !force {
 			doContinue := false
 
@@ -53,16 +56,17 @@ Examples:
 			}, &doContinue)
 
 			if !doContinue {
-				return
+				return nil
 			}
 		}
 
 		wiredoor.Disconnect()
 
-		err := wiredoor.RegenerateKeys()
+		_, err := wiredoor.RegenerateKeys(ctx)
 		if err != nil {
-			utils.Terminal().Errorf("Regenerate: %v", err)
+			return failed(cmd, fmt.Errorf("unable to regenerate keys: %w", err))
 		}
+		return nil
 	},
 }
 
@@ -50,6 +50,8 @@ Examples:
 	Example: `  # Regenerate keys and token for this node
   wiredoor regenerate`,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		if !force {
 			doContinue := false
 
@@ -92,7 +94,7 @@ Examples:
 				if response, ok := jsonResponse["response"].(string); ok {
 					switch response {
 					case "ok":
-						wiredoor.Status()
+						wiredoor.Status(ctx)
 						os.Exit(0)
 					default:
 						utils.Terminal().Errorf("Fail due to unhandled service reposnse: %v\n", response)
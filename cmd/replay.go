@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor/inspect"
+)
+
+var replayAddr string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <request-id>",
+	Short: "Send a request recorded by 'wiredoor http --inspect' to the backend again",
+	Long: `Send a request recorded by a running 'wiredoor http --inspect' to the backend
+again, with the same method, path, headers and body.
+
+The replay is recorded as a new request, so its response can be compared
+with the original one using 'wiredoor inspect'. Requests whose body was
+larger than the capture limit cannot be replayed.
+
+Optional flags:
+  --addr       Address of the inspector UI (default: 127.0.0.1:4040)`,
+	Example: `  # Find the request to replay
+  wiredoor inspect
+
+  # Replay request 12
+  wiredoor replay 12`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return failed(cmd, fmt.Errorf("invalid request ID %q", args[0]))
+		}
+
+		ex, err := inspect.NewClient(replayAddr).Replay(ctx, id)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().Render(ex, func() {
+			printExchanges([]inspect.Exchange{ex})
+		})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVar(&replayAddr, "addr", inspect.DefaultUIAddress, "Address of the inspector UI")
+}
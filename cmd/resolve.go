@@ -0,0 +1,27 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+// resolveServiceArgs accepts "<name|ID>" or "<type> <name|ID>" and returns
+// the matching service, inferring the type when it is not given.
+func resolveServiceArgs(ctx context.Context, args []string) (wiredoor.Service, error) {
+	serviceType := ""
+	ref := args[0]
+
+	if len(args) == 2 {
+		serviceType, ref = args[0], args[1]
+		if serviceType != "http" && serviceType != "tcp" {
+			return wiredoor.Service{}, fmt.Errorf("invalid service type %q: must be 'http' or 'tcp'", serviceType)
+		}
+	}
+
+	return wiredoor.ResolveService(ctx, serviceType, ref)
+}
@@ -4,15 +4,31 @@ Copyright © 2024 infladoor - <support@infladoor.com>
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/version"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
 )
 
-var showVersion bool
+var (
+	showVersion bool
+	output      string
+	profile     string
+	configPath  string
+)
+
+// createsProfile marks commands that write server settings and may be run
+// with a --profile that does not exist yet, to create it.
+var createsProfile = map[string]string{"createsProfile": "true"}
+
+// inspectsConfig marks commands that must see the config file unmigrated.
+var inspectsConfig = map[string]string{"createsProfile": "true", "inspectsConfig": "true"}
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -20,7 +36,21 @@ var rootCmd = &cobra.Command{
 	Short: "Wiredoor CLI - Ingress as a service",
 	Long:  "Wiredoor CLI allows you to connect, expose, and manage nodes and services securely with Wiredoor Server.",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		utils.InitConsole(utils.ConsoleOptions{})
+		format, err := utils.ParseOutputFormat(output)
+		if err != nil {
+			return err
+		}
+		utils.InitConsole(utils.ConsoleOptions{Output: format})
+
+		wiredoor.SetConfigLocation(configPath)
+		wiredoor.SetAutoMigrate(cmd.Annotations["inspectsConfig"] == "")
+		wiredoor.SetProfile(profile)
+		if cmd.Annotations["createsProfile"] == "" {
+			if err := wiredoor.CheckProfile(); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -42,8 +72,17 @@ var rootCmd = &cobra.Command{
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The command context is cancelled on the first Ctrl-C/SIGTERM so in-flight
+// API calls abort; a second signal terminates the process immediately.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -55,6 +94,9 @@ func init() {
 	// will be global for your application.
 	rootCmd.Root().CompletionOptions.DisableDefaultCmd = true
 	rootCmd.PersistentFlags().BoolVarP(&showVersion, "version", "v", false, "Show Wiredoor CLI version")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "Output format: table, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file to use (default: $WIREDOOR_CONFIG or "+wiredoor.SystemConfigLocation()+")")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Server profile to use (default: $WIREDOOR_PROFILE or the current context)")
 }
 
 func RootCmd() *cobra.Command {
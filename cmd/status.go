@@ -4,9 +4,12 @@ Copyright © 2024 Daniel Mesa <support@wiredoor.net>
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/wiredoor/wiredoor-cli/utils"
 	"github.com/wiredoor/wiredoor-cli/wiredoor"
 )
 
@@ -14,6 +17,7 @@ var (
 	checkHealth bool
 	watch       bool
 	interval    int
+	statusAll   bool
 )
 
 var statusCmd = &cobra.Command{
@@ -28,6 +32,12 @@ Optional flags allow you to:
   --health     Run a simple health check (for CI or monitoring)
   --watch      Continuously monitor connection and service status
   --interval   Interval in seconds to use with --watch (default: 5)
+  --all        Show the status of the tunnel of every server profile
+
+Backend health:
+  When [health] is enabled in the config file, --watch also probes the backend
+  of each enabled service, logs failures and can disable unhealthy services
+  until they recover. 'wiredoor status' then shows a HEALTH column.
 
 Examples:
   # Check status once
@@ -37,23 +47,50 @@ Examples:
   wiredoor status --health
 
   # Watch status continuously
-  wiredoor status --watch --interval 10`,
-	Run: func(cmd *cobra.Command, args []string) {
+  wiredoor status --watch --interval 10
+
+  # Status of every server profile
+  wiredoor status --all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		if statusAll {
+			if checkHealth || watch {
+				return failed(cmd, errors.New("--all cannot be used with --health or --watch"))
+			}
+			err := forEachProfile(func(p wiredoor.Profile) {
+				if p.Url == "" {
+					return
+				}
+				utils.Terminal().Section(fmt.Sprintf("Profile %s (%s)", p.Name, p.Interface))
+				wiredoor.Status(ctx)
+			})
+			if err != nil {
+				return failed(cmd, err)
+			}
+			return nil
+		}
+
 		if checkHealth {
-			wiredoor.Health()
-			return
+			wiredoor.Health(ctx)
+			return nil
 		}
 		if watch {
 			for {
-				wiredoor.WatchHealt()
+				wiredoor.WatchHealt(ctx)
 				sleepSeconds := interval
 				if sleepSeconds <= 0 {
 					sleepSeconds = 15
 				}
-				time.Sleep(time.Duration(sleepSeconds) * time.Second)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(time.Duration(sleepSeconds) * time.Second):
+				}
 			}
 		}
-		wiredoor.Status()
+		wiredoor.Status(ctx)
+		return nil
 	},
 }
 
@@ -63,4 +100,5 @@ func init() {
 	statusCmd.Flags().BoolVar(&checkHealth, "health", false, "Perform a quick health check (useful for CI or monitoring)")
 	statusCmd.Flags().BoolVar(&watch, "watch", false, "Continuously monitor connection status")
 	statusCmd.Flags().IntVar(&interval, "interval", 10, "Polling interval in seconds (used with --watch)")
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "Show the status of every server profile")
 }
@@ -20,6 +20,8 @@ var (
 	tcpAllowList   []string
 	tcpBlockList   []string
 	tcpTtl         string
+	tcpForeground  bool
+	tcpSkipCheck   bool
 )
 
 var tcpCmd = &cobra.Command{
@@ -45,10 +47,15 @@ Optional flags:
   --blockedIps     List of blocked source IPs or CIDRs
 	--ttl						 Time-to-live duration for the exposure (e.g., "30m", "1h", "2d").
                    Automatically disables the service after the specified duration.
+  --foreground     Keep running and disable the service on Ctrl+C or SIGTERM.
+                   A service that was already enabled is left enabled.
+  --skip-check     Expose the service even if the backend does not accept connections.
 
 How it works:
   - Wiredoor assigns a public port (e.g. 20000) on the gateway.
   - Incoming traffic to this port is forwarded to your service running on the local/private network.
+  - Before exposing, the backend must accept connections. UDP backends are only
+    rejected when they answer with "port unreachable".
 
 Examples:
   # Expose local service running on port 22 (SSH)
@@ -77,20 +84,43 @@ Examples:
   # Gateway mode with access restriction
   wiredoor tcp db --port 5432 --backendHost 10.1.0.15 --allowedIps 192.168.1.0/24`,
 	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
 		name := args[0]
 
-		node := wiredoor.GetNode()
+		serviceTtl, err := wiredoor.NormalizeTTL(tcpTtl)
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		node, err := wiredoor.GetNode(ctx)
+
+		if err != nil {
+			return failed(cmd, err)
+		}
 
 		if node.IsGateway && tcpBackendHost == "" {
 			utils.Terminal().Hint("You must define --backendHost when your node is a gateway")
-			return
+			return exitError(cmd)
+		}
+
+		if !tcpSkipCheck && !checkBackend(tcpBackendHost, tcpPort, tcpProto) {
+			return exitError(cmd)
 		}
 
 		utils.Terminal().StartProgress("Configuring " + strings.ToUpper(tcpProto) + " service...")
 		defer utils.Terminal().StopProgress()
 
-		wiredoor.ExposeTCP(wiredoor.TcpServiceParams{
+		previouslyEnabled := false
+		if tcpForeground {
+			previouslyEnabled, err = wasEnabled(ctx, "tcp", name)
+			if err != nil {
+				return failed(cmd, err)
+			}
+		}
+
+		service, err := wiredoor.ExposeTCP(ctx, wiredoor.TcpServiceParams{
 			Name:        name,
 			Domain:      tcpDomain,
 			Proto:       tcpProto,
@@ -99,8 +129,30 @@ Examples:
 			AllowedIps:  tcpAllowList,
 			BlockedIps:  tcpBlockList,
 			Ssl:         tcpSSL,
-			Ttl:         tcpTtl,
-		}, node)
+			Ttl:         serviceTtl,
+		})
+
+		if err != nil {
+			return failed(cmd, err)
+		}
+
+		utils.Terminal().FinalizeProgress()
+		utils.Terminal().Section(strings.ToUpper(service.Proto) + " Service Available")
+
+		utils.Terminal().Render(service, func() {
+			wiredoor.PrintTcpServices([]wiredoor.TcpService{service}, node.IsGateway)
+		})
+
+		if tcpForeground {
+			holdExposures(ctx, []exposure{{
+				service:     wiredoor.Service{Type: "tcp", Tcp: &service},
+				wasEnabled:  previouslyEnabled,
+				backendHost: backendTarget(tcpBackendHost),
+				backendPort: tcpPort,
+				udp:         service.Proto == "udp",
+			}})
+		}
+		return nil
 	},
 }
 
@@ -115,4 +167,6 @@ func init() {
 	tcpCmd.Flags().StringSliceVar(&tcpAllowList, "allowedIps", nil, "List of allowed IPs or CIDRs")
 	tcpCmd.Flags().StringSliceVar(&tcpBlockList, "blockedIps", nil, "List of blocked IPs or CIDRs")
 	tcpCmd.Flags().StringVar(&tcpTtl, "ttl", "", "Time-to-live duration for the exposure (e.g., 30m, 1h, 2d)")
+	tcpCmd.Flags().BoolVar(&tcpForeground, "foreground", false, "Keep running and disable the service on exit")
+	tcpCmd.Flags().BoolVar(&tcpSkipCheck, "skip-check", false, "Do not check that the backend accepts connections")
 }
@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Daniel Mesa <support@wiredoor.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+// confirmServerCertificate implements trust-on-first-use for login: when the
+// server certificate cannot be verified, it shows the key fingerprint and,
+// if the user accepts it, pins it in config.ini.
+func confirmServerCertificate(server string) error {
+	if !strings.HasPrefix(strings.ToLower(server), "https://") {
+		return nil
+	}
+
+	current := wiredoor.GetServerConfig()
+	opts := current.TLSOptions()
+
+	// A stored pin belongs to the previously configured server only.
+	if current.Url != server {
+		opts.PinSHA256 = ""
+	}
+
+	if opts.Insecure {
+		utils.Terminal().Warnf("TLS verification is disabled ([server] insecure = true)")
+		return nil
+	}
+
+	info, err := wiredoor.InspectServerCertificate(server, opts, wiredoor.GetClientConfig().Proxy)
+	if err != nil {
+		return err
+	}
+
+	if info.PinMatches {
+		return nil
+	}
+
+	if info.Trusted && opts.PinSHA256 == "" {
+		if current.PinSHA256 != "" {
+			return wiredoor.SaveServerTrust(server, "")
+		}
+		return nil
+	}
+
+	if opts.PinSHA256 != "" {
+		utils.Terminal().Warnf("The server key does not match the pinned key in %s", wiredoor.GetConfigLocation())
+	} else {
+		utils.Terminal().Warnf("The server certificate is not trusted by this system")
+	}
+
+	utils.Terminal().KV("Subject", info.Subject)
+	utils.Terminal().KV("Issuer", info.Issuer)
+	utils.Terminal().KV("Expires", info.NotAfter.Format("2006-01-02"))
+	utils.Terminal().KV("SHA-256 key fingerprint", info.Fingerprint)
+
+	trust := false
+
+	survey.AskOne(&survey.Confirm{
+		Message: "Trust this server and pin its key in config.ini?",
+		Default: trust,
+	}, &trust)
+
+	if !trust {
+		return errors.New("server certificate not trusted")
+	}
+
+	return wiredoor.SaveServerTrust(server, info.Fingerprint)
+}
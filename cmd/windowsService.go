@@ -7,6 +7,7 @@ Copyright © 2024 Daniel Mesa <support@wiredoor.net>
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -60,6 +61,7 @@ func manageIncomingData(data []byte, wiredoorPipeHandle windows.Handle) {
 					}
 					if !wiredoor.WireguardInterfaceExists() {
 						err := wiredoor.ConnectApi(
+							context.Background(),
 							wiredoor.ConnectionConfig{
 								URL:       url,
 								Token:     token,
@@ -81,7 +83,7 @@ func manageIncomingData(data []byte, wiredoorPipeHandle windows.Handle) {
 					sendResponse("ok", wiredoorPipeHandle)
 				case "regenerate":
 					wiredoor.Disconnect()
-					if err := wiredoor.RegenerateKeys(); err != nil {
+					if _, err := wiredoor.RegenerateKeys(context.Background()); err != nil {
 						sendResponse(fmt.Sprintf("SERVICE DOWN:Regenerate error: %v", err), wiredoorPipeHandle)
 					} else {
 						sendResponse("ok", wiredoorPipeHandle)
@@ -95,7 +97,15 @@ func manageIncomingData(data []byte, wiredoorPipeHandle windows.Handle) {
 					if !ok {
 						token = ""
 					}
-					err := wiredoor.SaveServerConfig(url, token)
+					clientCert, _ := jsonObject["client_cert"].(string)
+					clientKey, _ := jsonObject["client_key"].(string)
+					var err error
+					if url != "" {
+						err = wiredoor.SaveServerConfig(url, token)
+					}
+					if err == nil && clientCert != "" {
+						err = wiredoor.SaveClientCertificate(clientCert, clientKey)
+					}
 					if err != nil {
 						slog.Error(fmt.Sprintf("[%s config] %v", utils.WiredoorServiceName, err))
 						sendResponse(fmt.Sprintf("%v", err.Error()), wiredoorPipeHandle)
@@ -218,7 +228,7 @@ func (wsvc *wiredoorWindowsService) Execute(args []string, r <-chan svc.ChangeRe
 			//wait 10 seconds before new check
 			time.Sleep(time.Duration(sleepSeconds) * time.Second)
 			monitoringMutex.Lock()
-			wiredoor.WatchHealt()
+			wiredoor.WatchHealt(context.Background())
 			monitoringMutex.Unlock()
 		}
 	}()
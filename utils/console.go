@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/mattn/go-isatty"
+	"gopkg.in/yaml.v3"
 )
 
 type ProgressMode string
@@ -21,6 +23,26 @@ const (
 	ProgressNever  ProgressMode = "never"
 )
 
+type OutputFormat string
+
+const (
+	OutputTable OutputFormat = "table"
+	OutputJSON  OutputFormat = "json"
+	OutputYAML  OutputFormat = "yaml"
+)
+
+// ParseOutputFormat validates the value of the --output flag.
+func ParseOutputFormat(v string) (OutputFormat, error) {
+	switch f := OutputFormat(strings.ToLower(strings.TrimSpace(v))); f {
+	case "", OutputTable:
+		return OutputTable, nil
+	case OutputJSON, OutputYAML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: must be table, json or yaml", v)
+	}
+}
+
 type ConsoleOptions struct {
 	Out io.Writer // default os.Stdout
 	Err io.Writer // default os.Stderr
@@ -28,6 +50,11 @@ type ConsoleOptions struct {
 	Quiet bool
 
 	ProgressMode ProgressMode // auto|always|never
+
+	// Output selects structured output. With json or yaml, human output
+	// and spinners are suppressed, Render writes values to Out and
+	// diagnostics are written to Err as JSON objects.
+	Output OutputFormat // table|json|yaml, default table
 }
 
 type Console struct {
@@ -36,7 +63,8 @@ type Console struct {
 
 	quiet bool
 
-	mode ProgressMode
+	mode   ProgressMode
+	output OutputFormat
 
 	mu   sync.Mutex
 	sp   *spinner.Spinner
@@ -74,15 +102,25 @@ func NewConsole(opts ConsoleOptions) *Console {
 	if mode == "" {
 		mode = ProgressAuto
 	}
+	output := opts.Output
+	if output == "" {
+		output = OutputTable
+	}
 
 	return &Console{
-		out:   out,
-		err:   errW,
-		quiet: opts.Quiet,
-		mode:  mode,
+		out:    out,
+		err:    errW,
+		quiet:  opts.Quiet || output != OutputTable,
+		mode:   mode,
+		output: output,
 	}
 }
 
+// Structured reports whether --output json or yaml is in effect.
+func (c *Console) Structured() bool {
+	return c.output != OutputTable
+}
+
 // ---------- Human output (stdout)
 
 func (c *Console) Blank() {
@@ -141,18 +179,95 @@ func (c *Console) Table(headers []string, rows [][]string) {
 	_ = w.Flush()
 }
 
+// ---------- Structured output (stdout)
+
+// Render writes v in the selected structured format, or calls human (if
+// not nil) in table mode.
+func (c *Console) Render(v any, human func()) {
+	switch c.output {
+	case OutputJSON:
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			c.Errorf("unable to encode output: %v", err)
+		}
+	case OutputYAML:
+		if err := WriteYAML(c.out, v); err != nil {
+			c.Errorf("unable to encode output: %v", err)
+		}
+	default:
+		if human != nil {
+			human()
+		}
+	}
+}
+
+// WriteYAML encodes v through its JSON form, so the keys follow the json
+// struct tags and keep their order.
+func WriteYAML(w io.Writer, v any) error {
+	node, err := YAMLNode(v)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// YAMLNode converts v to a block style YAML document through its JSON form.
+func YAMLNode(v any) (*yaml.Node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+
+	return &node, nil
+}
+
+// blockStyle drops the flow style and quoting inherited from JSON.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
 // ---------- Diagnostics (stderr)
 
 func (c *Console) Warnf(format string, args ...any) {
 	c.StopProgress()
+	if c.Structured() {
+		c.StructuredError(map[string]string{"level": "warning", "message": strings.TrimSpace(fmt.Sprintf(format, args...))})
+		return
+	}
 	fmt.Fprintf(c.err, "WARN: "+format+"\n", args...)
 }
 
 func (c *Console) Errorf(format string, args ...any) {
 	c.StopProgress()
+	if c.Structured() {
+		c.StructuredError(map[string]string{"level": "error", "message": strings.TrimSpace(fmt.Sprintf(format, args...))})
+		return
+	}
 	fmt.Fprintf(c.err, "ERROR: "+format+"\n", args...)
 }
 
+// StructuredError writes v to stderr as a single line of JSON.
+func (c *Console) StructuredError(v any) {
+	c.StopProgress()
+	_ = json.NewEncoder(c.err).Encode(v)
+}
+
 // ---------- Spinner (stderr)
 
 func (c *Console) StartProgress(msg string) {
@@ -191,6 +306,9 @@ func (c *Console) StopProgress() {
 }
 
 func (c *Console) progressEnabled() bool {
+	if c.Structured() {
+		return false
+	}
 	switch c.mode {
 	case ProgressNever:
 		return false
@@ -207,6 +325,9 @@ func (c *Console) FinalizeProgress() {
 }
 
 func (c *Console) clearErrLine() {
+	if c.Structured() {
+		return
+	}
 	fmt.Fprint(c.err, "\r\033[2K")
 	fmt.Fprintf(c.out, "        \r") // windows override
 }
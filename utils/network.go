@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -12,17 +14,25 @@ import (
 )
 
 func CheckPort(host string, port int) bool {
+	if err := DialPort(host, port, 2*time.Second); err != nil {
+		Terminal().Errorf("Port %d is closed or unreachable: %v\n", port, err)
+		return false
+	}
+
+	return true
+}
+
+// DialPort is the silent form of CheckPort: it returns why host:port does
+// not accept TCP connections, or nil.
+func DialPort(host string, port int, timeout time.Duration) error {
 	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	timeout := 2 * time.Second
 
 	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
-		Terminal().Errorf("Port %d is closed or unreachable: %v\n", port, err)
-		return false
+		return err
 	}
-	defer conn.Close()
 
-	return true
+	return conn.Close()
 }
 
 func LocalTunnelIP(tunnel string) string {
@@ -141,3 +151,42 @@ func InterfaceExists(ifaceName string) bool {
 	}
 	return false
 }
+
+// ProbeBackend checks that a backend accepts connections on host:port.
+// With proto "https" a TLS handshake is also made, without verifying the
+// certificate as backends often use self-signed ones. UDP is best-effort:
+// only an explicit ICMP port unreachable reply is reported as an error.
+func ProbeBackend(host string, port int, proto string, timeout time.Duration) error {
+	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+
+	switch proto {
+	case "https":
+		dialer := &net.Dialer{Timeout: timeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		return conn.Close()
+	case "udp":
+		conn, err := net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte{}); err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		if _, err := conn.Read(make([]byte, 1)); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		return nil
+	default:
+		return DialPort(host, port, timeout)
+	}
+}
@@ -1,29 +1,24 @@
 package wiredoor
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
-	"errors"
-	"io"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"net/url"
-	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/wiredoor/wiredoor-cli/utils"
-	"github.com/wiredoor/wiredoor-cli/version"
 )
 
 type apiRequest struct {
-	Server  string
 	Method  string
 	Path    string
 	Body    []byte
-	Token   string
 	Timeout int
+	NoRetry bool // never retried, for calls whose effect a lost answer would repeat
 }
 
 type EnableRequest struct {
@@ -74,23 +69,33 @@ type TcpServiceParams struct {
 type HttpService struct {
 	ID int64 `json:"id"`
 	HttpServiceParams
-	NodeId       int64     `json:"nodeId"`
-	Node         Node      `json:"node"`
-	Enabled      bool      `json:"enabled"`
-	PublicAccess string    `json:"publicAccess"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	NodeId       int64      `json:"nodeId"`
+	Node         Node       `json:"node,omitzero"`
+	Enabled      bool       `json:"enabled"`
+	PublicAccess string     `json:"publicAccess"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type TcpService struct {
 	ID int64 `json:"id"`
 	TcpServiceParams
-	NodeId       int64     `json:"nodeId"`
-	Node         Node      `json:"node"`
-	Enabled      bool      `json:"enabled"`
-	PublicAccess string    `json:"publicAccess"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	NodeId       int64      `json:"nodeId"`
+	Node         Node       `json:"node,omitzero"`
+	Enabled      bool       `json:"enabled"`
+	PublicAccess string     `json:"publicAccess"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Service is either an HTTP or a TCP service: exactly one of Http or Tcp
+// is set, depending on Type.
+type Service struct {
+	Type string
+	Http *HttpService
+	Tcp  *TcpService
 }
 
 type PAT struct {
@@ -135,6 +140,18 @@ type NodeInfo struct {
 	TransferRx               int64  `json:"transferRx"`
 	TransferTx               int64  `json:"transferTx"`
 	Status                   string `json:"status"`
+
+	// legacyGateway is set when the server only sent gatewayNetwork.
+	legacyGateway bool
+}
+
+// normalize converts the legacy gatewayNetwork field of older servers to
+// GatewayNetworks, so callers only deal with the latter.
+func (n *NodeInfo) normalize() {
+	if len(n.GatewayNetworks) == 0 && n.GatewayNetwork != "" {
+		n.GatewayNetworks = []GatewayNetwork{{Subnet: n.GatewayNetwork}}
+		n.legacyGateway = true
+	}
 }
 
 type PeerEndpoint struct {
@@ -189,323 +206,157 @@ type UpdateGatewayParams struct {
 	GatewayNetwork   string `json:"gatewayNetwork"`
 }
 
-func AdminLogin(server string, username string, password string) (string, error) {
-	body, _ := json.Marshal(AdminCredentials{
-		Username: username,
-		Password: password,
-	})
-
-	resp := requestApi(apiRequest{Server: server, Method: "POST", Path: "/auth/login", Body: body})
-
-	if resp != nil {
-		response := adminLoginResponse{}
+// DefaultClient returns an API client built from the local config file.
+// When the node token cannot be resolved, requests return why.
+func DefaultClient() *Client {
+	config := getConfig()
 
-		err := json.Unmarshal(resp, &response)
+	token, tokenErr := config.Server.ResolveToken()
 
-		if err != nil {
-			return "", err
-		}
-
-		if response.Token != "" {
-			return response.Token, nil
-		}
+	// ClientOptions treats 0 as "use the default"; in config.ini it means
+	// no retries.
+	retries := parseInt(config.Client.Retries, defaultRetries)
+	if retries == 0 {
+		retries = -1
 	}
 
-	return "", errors.New("authentication failed")
-}
-
-func ConfigureNode(server string, token string, node NodeParams) (Node, error) {
-	body, _ := json.Marshal(node)
-
-	resp := requestApi(apiRequest{Server: server, Token: token, Method: "POST", Path: "/nodes", Body: body})
-
-	if resp != nil {
-		node := Node{}
-
-		err := json.Unmarshal(resp, &node)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unmarshal: %v", err)
-		}
-
-		if node.Token != "" {
-			SaveServerConfig(server, node.Token)
-			return node, nil
-		}
+	client := NewClient(ClientOptions{
+		BaseURL:    config.Server.Url,
+		Token:      token,
+		PathPrefix: config.Server.Path,
+		TLS:        config.Server.TLSOptions(),
+		Proxy:      config.Client.Proxy,
+		Timeout:    time.Duration(parseInt(config.Client.Timeout, 0)) * time.Second,
+		Retries:    retries,
+		Logger:     slog.Default(),
+	})
+	if tokenErr != nil {
+		client.tokenErr = fmt.Errorf("unable to get the node token: %w", tokenErr)
 	}
-
-	return Node{}, errors.New("unable to retrieve node configuration")
+	return client
 }
 
-func GetNode() NodeInfo {
-	resp := requestApi(apiRequest{Method: "GET", Path: "/cli/node"})
-
-	if resp != nil {
-		node := NodeInfo{}
-
-		err := json.Unmarshal(resp, &node)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to retrieve node information: %v", err)
-		}
-
-		return node
-	}
-
-	return NodeInfo{}
+func AdminLogin(ctx context.Context, server string, username string, password string) (string, error) {
+	return DefaultClient().WithBaseURL(server).WithToken("").AdminLogin(ctx, username, password)
 }
 
-func GetServices() []HttpService {
-	// !!!NOT USED ???
-	utils.Terminal().Println("Getting HTTP services...")
-	resp := requestApi(apiRequest{Method: "GET", Path: "/cli/services/http"})
-
-	if resp != nil {
-		services := []HttpService{}
+// ConfigureNode registers a node using an admin token and saves the new
+// node token to the config file.
+func ConfigureNode(ctx context.Context, server string, token string, node NodeParams) (Node, error) {
+	created, err := DefaultClient().WithBaseURL(server).WithToken(token).ConfigureNode(ctx, node)
 
-		err := json.Unmarshal(resp, &services)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to retrieve HTTP services: %v", err)
-		}
-
-		return services
+	if err != nil {
+		return Node{}, err
 	}
 
-	return []HttpService{}
-}
-
-func GetTcpServices() []TcpService {
-	// !!! NOT USED
-	utils.Terminal().Println("Getting TCP services...")
-	resp := requestApi(apiRequest{Method: "GET", Path: "/cli/services/tcp"})
-
-	if resp != nil {
-		services := []TcpService{}
-
-		err := json.Unmarshal(resp, &services)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to retrieve TCP services: %v", err)
-		}
-
-		return services
+	if err := SaveServerConfig(server, created.Token); err != nil {
+		return created, err
 	}
 
-	return []TcpService{}
+	return created, nil
 }
 
-func GetNodeConfig() string {
-	resp := requestApi(apiRequest{Method: "GET", Path: "/cli/config"})
-
-	if resp != nil {
-		return strings.ReplaceAll(strings.Trim(string(resp), "\""), "\\n", "\n")
-	}
-
-	return ""
+func GetNode(ctx context.Context) (NodeInfo, error) {
+	return DefaultClient().GetNode(ctx)
 }
 
-func GetApiConfig() ApiConfig {
-	resp := requestApi(apiRequest{Method: "GET", Path: "/config", Timeout: 5})
-
-	if resp != nil {
-		config := ApiConfig{}
+func GetServices(ctx context.Context) ([]HttpService, error) {
+	return DefaultClient().GetServices(ctx)
+}
 
-		err := json.Unmarshal(resp, &config)
+func GetTcpServices(ctx context.Context) ([]TcpService, error) {
+	return DefaultClient().GetTcpServices(ctx)
+}
 
-		if err != nil {
-			utils.Terminal().Errorf("Unable to retrieve API configuration: %v", err)
-		}
+func GetNodeConfig(ctx context.Context) (string, error) {
+	return DefaultClient().GetNodeConfig(ctx)
+}
 
-		return config
-	}
+func GetApiConfig(ctx context.Context) (ApiConfig, error) {
+	return DefaultClient().GetApiConfig(ctx)
+}
 
-	return ApiConfig{}
+func GetNodeWGConfig(ctx context.Context) (WGConfig, error) {
+	return DefaultClient().GetNodeWGConfig(ctx)
 }
 
-func GetNodeWGConfig() WGConfig {
-	resp := requestApi(apiRequest{Method: "GET", Path: "/cli/wgconfig"})
+// RegenerateKeys rotates the node credentials, stores the new token and
+// reconnects the tunnel.
+func RegenerateKeys(ctx context.Context) (Node, error) {
+	node, err := DefaultClient().RegenerateKeys(ctx)
 
-	if resp != nil {
-		config := WGConfig{}
+	if err != nil {
+		return Node{}, err
+	}
 
-		err := json.Unmarshal(resp, &config)
+	config := getConfig()
 
-		if err != nil {
-			utils.Terminal().Errorf("Unable to retrieve WireGuard configuration: %v", err)
-		}
+	if err := SaveServerConfig(config.Server.Url, node.Token); err != nil {
+		return node, err
+	}
 
-		return config
+	if err := Connect(ctx, ConnectionConfig{}); err != nil {
+		return node, err
 	}
 
-	return WGConfig{}
+	return node, nil
 }
 
-func RegenerateKeys() error {
-	resp := requestApi(apiRequest{Method: "PATCH", Path: "/cli/regenerate"})
-
-	if resp != nil {
-		node := Node{}
-
-		err := json.Unmarshal(resp, &node)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to regenerate keys: %v", err)
-		}
-
-		config := getConfig()
-
-		if node.Token != "" {
-			SaveServerConfig(config.Server.Url, node.Token)
-			Connect(ConnectionConfig{})
-		} else {
-			if err != nil {
-				utils.Terminal().Errorf("Unable to regenerate keys. Warning node config error:%v", err)
-				return err
-			}
-			utils.Terminal().Errorf("Unable to regenerate keys. No token received.")
-			return errors.New("unable to regenerate keys. No token received")
-		}
-	}
-	return nil
+func ExposeHTTP(ctx context.Context, service HttpServiceParams) (HttpService, error) {
+	return DefaultClient().ExposeHTTP(ctx, service)
 }
 
-func ExposeHTTP(service HttpServiceParams, node NodeInfo) {
-	body, _ := json.Marshal(service)
-
-	resp := requestApi(apiRequest{Method: "POST", Path: "/cli/expose/http", Body: body})
-
-	if resp != nil {
-		createdService := HttpService{}
-
-		err := json.Unmarshal(resp, &createdService)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to expose HTTP service: %v", err)
-			return
-		}
-		utils.Terminal().FinalizeProgress()
-		utils.Terminal().Section("HTTP Service Available")
-
-		PrintHttpServices([]HttpService{createdService}, node.IsGateway)
-	}
+func ExposeTCP(ctx context.Context, service TcpServiceParams) (TcpService, error) {
+	return DefaultClient().ExposeTCP(ctx, service)
 }
 
-func ExposeTCP(service TcpServiceParams, node NodeInfo) {
-	body, _ := json.Marshal(service)
-
-	resp := requestApi(apiRequest{Method: "POST", Path: "/cli/expose/tcp", Body: body})
-
-	if resp != nil {
-		createdService := TcpService{}
-
-		err := json.Unmarshal(resp, &createdService)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to expose TCP service: %v", err)
-			return
-		}
-
-		utils.Terminal().FinalizeProgress()
-		utils.Terminal().Section(strings.ToUpper(createdService.Proto) + " Service Available")
-
-		PrintTcpServices([]TcpService{createdService}, node.IsGateway)
-	}
+func DisableServiceByType(ctx context.Context, serviceType string, id string) (Service, error) {
+	return DefaultClient().DisableService(ctx, serviceType, id)
 }
 
-func DisableServiceByType(serviceType string, id string) {
-	resp := requestApi(apiRequest{Method: "PATCH", Path: "/cli/services/" + serviceType + "/" + id + "/disable"})
-
-	if resp != nil {
-		utils.Terminal().FinalizeProgress()
-		if serviceType == "http" {
-			service := HttpService{}
-
-			err := json.Unmarshal(resp, &service)
-
-			if err != nil {
-				utils.Terminal().Errorf("Unable to disable service: %v", err)
-			}
-
-			utils.Terminal().Section("Service Disabled Successfully!")
-
-			PrintHttpServices([]HttpService{service}, service.BackendHost != "")
-		}
-		if serviceType == "tcp" {
-			service := TcpService{}
-
-			err := json.Unmarshal(resp, &service)
-
-			if err != nil {
-				utils.Terminal().Errorf("Unable to disable service: %v", err)
-			}
+func EnableServiceByType(ctx context.Context, params EnableRequest) (Service, error) {
+	return DefaultClient().EnableService(ctx, params)
+}
 
-			utils.Terminal().Section("Service Disabled Successfully!")
+func UpdateGatewaySubnet(ctx context.Context, network GatewayNetwork) (NodeInfo, error) {
+	return DefaultClient().UpdateGatewaySubnet(ctx, network)
+}
 
-			PrintTcpServices([]TcpService{service}, service.BackendHost != "")
-		}
+// MarshalJSON flattens the service: the HTTP or TCP service fields plus
+// its "type".
+func (s Service) MarshalJSON() ([]byte, error) {
+	switch {
+	case s.Http != nil:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			*HttpService
+		}{s.Type, s.Http})
+	case s.Tcp != nil:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			*TcpService
+		}{s.Type, s.Tcp})
+	default:
+		return []byte("null"), nil
 	}
 }
 
-func EnableServiceByType(params EnableRequest) {
-	var body []byte
-
-	if params.Ttl != "" {
-		body, _ = json.Marshal(EnableParams{Ttl: params.Ttl})
+// Print writes the service as a single-row HTTP or TCP table.
+func (s Service) Print() {
+	if s.Http != nil {
+		PrintHttpServices([]HttpService{*s.Http}, s.Http.BackendHost != "")
 	}
-
-	resp := requestApi(apiRequest{Method: "PATCH", Path: "/cli/services/" + params.ServiceType + "/" + params.ID + "/enable", Body: body})
-
-	if resp != nil {
-		utils.Terminal().FinalizeProgress()
-		if params.ServiceType == "http" {
-			service := HttpService{}
-
-			err := json.Unmarshal(resp, &service)
-
-			if err != nil {
-				utils.Terminal().Errorf("Unable to enable service: %v", err)
-			}
-
-			utils.Terminal().Section("Service Enabled Successfully!")
-
-			PrintHttpServices([]HttpService{service}, service.BackendHost != "")
-		}
-		if params.ServiceType == "tcp" {
-			service := TcpService{}
-
-			err := json.Unmarshal(resp, &service)
-
-			if err != nil {
-				utils.Terminal().Errorf("Unable to enable service: %v", err)
-			}
-
-			utils.Terminal().Section("Service Enabled Successfully!")
-
-			PrintTcpServices([]TcpService{service}, service.BackendHost != "")
-		}
+	if s.Tcp != nil {
+		PrintTcpServices([]TcpService{*s.Tcp}, s.Tcp.BackendHost != "")
 	}
 }
 
-func UpdateGatewaySubnet(network GatewayNetwork) {
-	body, _ := json.Marshal(UpdateGatewayParams{GatewayNetwork: network.Subnet, GatewayInterface: network.Interface})
-
-	resp := requestApi(apiRequest{Method: "PATCH", Path: "/cli/node/gateway", Body: body})
-
-	if resp != nil {
-		node := NodeInfo{}
-
-		err := json.Unmarshal(resp, &node)
-
-		if err != nil {
-			utils.Terminal().Errorf("Unable to update gateway subnet: %v", err)
-		}
-
-		utils.Terminal().Section("Subnet successfully updated to: " + node.GatewayNetwork)
-	}
+func PrintHttpServices(services []HttpService, isGateway bool) {
+	printHttpServices(services, isGateway, nil)
 }
 
-func PrintHttpServices(services []HttpService, isGateway bool) {
+// printHttpServices adds a HEALTH column when health is set.
+func printHttpServices(services []HttpService, isGateway bool, health *HealthState) {
 	utils.Terminal().Section("  HTTP:")
 	if len(services) == 0 {
 		utils.Terminal().KV("HTTP", "none")
@@ -521,16 +372,25 @@ func PrintHttpServices(services []HttpService, isGateway bool) {
 
 		target := buildHttpTarget(svc, isGateway)
 
-		rows = append(rows, []string{
+		row := []string{
 			strconv.FormatInt(int64(svc.ID), 10),
 			enabled,
 			svc.Name,
 			svc.PublicAccess,
 			target,
-		})
+			formatRemaining(svc.ExpiresAt),
+		}
+		if health != nil {
+			row = append(row, health.label("http", svc.ID))
+		}
+		rows = append(rows, row)
 	}
 
-	utils.Terminal().Table([]string{"ID", "STATE", "NAME", "PUBLIC", "TARGET"}, rows)
+	headers := []string{"ID", "STATE", "NAME", "PUBLIC", "TARGET", "EXPIRES"}
+	if health != nil {
+		headers = append(headers, "HEALTH")
+	}
+	utils.Terminal().Table(headers, rows)
 }
 
 func buildHttpTarget(svc HttpService, isGateway bool) string {
@@ -542,6 +402,11 @@ func buildHttpTarget(svc HttpService, isGateway bool) string {
 }
 
 func PrintTcpServices(services []TcpService, isGateway bool) {
+	printTcpServices(services, isGateway, nil)
+}
+
+// printTcpServices adds a HEALTH column when health is set.
+func printTcpServices(services []TcpService, isGateway bool, health *HealthState) {
 	utils.Terminal().Section("  TCP:")
 
 	if len(services) == 0 {
@@ -563,17 +428,26 @@ func PrintTcpServices(services []TcpService, isGateway bool) {
 
 		target := buildTcpTarget(svc, isGateway)
 
-		rows = append(rows, []string{
+		row := []string{
 			strconv.FormatInt(int64(svc.ID), 10),
 			state,
 			svc.Name,
 			proto,
 			svc.PublicAccess,
 			target,
-		})
+			formatRemaining(svc.ExpiresAt),
+		}
+		if health != nil {
+			row = append(row, health.label("tcp", svc.ID))
+		}
+		rows = append(rows, row)
 	}
 
-	utils.Terminal().Table([]string{"ID", "STATE", "NAME", "PROTO", "PUBLIC", "TARGET"}, rows)
+	headers := []string{"ID", "STATE", "NAME", "PROTO", "PUBLIC", "TARGET", "EXPIRES"}
+	if health != nil {
+		headers = append(headers, "HEALTH")
+	}
+	utils.Terminal().Table(headers, rows)
 }
 
 func buildTcpTarget(svc TcpService, isGateway bool) string {
@@ -584,112 +458,35 @@ func buildTcpTarget(svc TcpService, isGateway bool) string {
 	return svc.Proto + "://localhost:" + port
 }
 
-func requestApi(request apiRequest) []byte {
-	config := getConfig()
-
-	server := config.Server.Url
-
-	if request.Server != "" {
-		server = request.Server
-	}
-
-	base, _ := url.Parse(server)
-	base.Path = path.Join(base.Path, path.Join(config.Server.Path, "/api", request.Path))
-
-	timeout := 20
-
-	if request.Timeout > 0 {
-		timeout = request.Timeout
-	}
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   time.Second * time.Duration(timeout),
-	}
-
-	// fmt.Println("Requesting", base.String())
-
-	req, err := http.NewRequest(request.Method, base.String(), bytes.NewBuffer(request.Body))
-
-	if err != nil {
-		utils.Terminal().Errorf("Unable to perform request: %v", err)
-	}
-
-	var token string
-
-	if request.Token != "" {
-		token = request.Token
-	} else {
-		token = config.Server.Token
-	}
-
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "wiredoor-cli/"+version.Version)
-
-	if token != "" {
-		req.Header.Add("Authorization", "Bearer "+token)
-	}
-
-	resp, err := client.Do(req)
-
-	if err != nil {
-		utils.Terminal().Errorf("Request failed: %v", err)
-		return nil
-	}
-
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-
-	if resp.StatusCode == 400 {
+func responseError(status int, body []byte) *APIError {
+	switch {
+	case status == http.StatusBadRequest && strings.Contains(string(body), "SSL certificate"):
+		// nginx answers 400 with an HTML page when ssl_verify_client fails
+		return &APIError{StatusCode: status, Message: "server requires a valid client certificate", Err: ErrClientCertificateRejected}
+	case status == http.StatusBadRequest:
 		errorRes := BadRequest{}
 
-		_ = json.Unmarshal(bodyBytes, &errorRes)
-
-		utils.Terminal().Errorf("Bad Request: %s", errorRes.Message)
-		return nil
-	}
-
-	if resp.StatusCode == 403 || resp.StatusCode == 401 {
-		utils.Terminal().Errorf("Invalid authentication token")
-		return nil
-	}
-
-	if resp.StatusCode == 404 {
-		utils.Terminal().Errorf("Server not found. Please check your server URL configuration.")
-		return nil
-	}
+		_ = json.Unmarshal(body, &errorRes)
 
-	if resp.StatusCode == 422 {
+		return &APIError{StatusCode: status, Message: "bad request: " + errorRes.Message}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &APIError{StatusCode: status, Message: "invalid authentication token"}
+	case status == http.StatusNotFound:
+		return &APIError{StatusCode: status, Message: "server not found. Please check your server URL configuration"}
+	case status == http.StatusUnprocessableEntity:
 		errorRes := UnprocessableRequest{}
 
-		_ = json.Unmarshal(bodyBytes, &errorRes)
-
-		if len(errorRes.Errors.Body) > 0 {
-			for _, v := range errorRes.Errors.Body {
-				utils.Terminal().Errorf(" -> %s: %s", v.Field, v.Message)
-			}
-		}
-
-		return nil
-	}
-
-	if resp.StatusCode >= 500 {
-		utils.Terminal().Errorf("Unknown Wiredoor server error")
-		return nil
-	}
+		_ = json.Unmarshal(body, &errorRes)
 
-	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "application/json") {
-		utils.Terminal().Errorf("Unexpected response format: %s", resp.Header.Get("Content-Type"))
-		return nil
-	}
+		validation := append([]ValidationError{}, errorRes.Errors.Params...)
+		validation = append(validation, errorRes.Errors.Body...)
 
-	if err != nil {
-		utils.Terminal().Errorf("Unable to read body response: %v", err)
+		return &APIError{StatusCode: status, Message: errorRes.Message, ValidationErrors: validation}
+	case status >= 500:
+		return &APIError{StatusCode: status, Message: "unknown Wiredoor server error"}
+	case status >= 300:
+		return &APIError{StatusCode: status, Message: "unexpected response status " + strconv.Itoa(status)}
 	}
 
-	return bodyBytes
+	return nil
 }
@@ -0,0 +1,292 @@
+package wiredoor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionEnable    = "enable"
+	ActionDisable   = "disable"
+	ActionUnchanged = "unchanged"
+)
+
+// PlanStep is one change needed to make a node match a manifest.
+type PlanStep struct {
+	Action  string   `json:"action"`
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	ID      int64    `json:"id,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	Disable bool     `json:"disable,omitempty"` // disable right after create/update
+
+	http     *HttpServiceParams
+	tcp      *TcpServiceParams
+	networks []GatewayNetwork
+}
+
+// Plan compares the manifest with the services of node, matching them by
+// name. Services missing from the manifest are disabled when prune is set
+// and left alone otherwise.
+func (m Manifest) Plan(node NodeInfo, prune bool) []PlanStep {
+	var plan []PlanStep
+
+	if len(m.GatewayNetworks) > 0 && node.IsGateway {
+		if changes := diffNetworks(node.GatewayNetworks, m.GatewayNetworks); len(changes) > 0 {
+			plan = append(plan, PlanStep{Action: ActionUpdate, Type: "gateway", Name: node.Name, Changes: changes, networks: m.GatewayNetworks})
+		}
+	}
+
+	for _, entry := range m.Http {
+		desired := entry.normalize()
+		idx := slices.IndexFunc(node.HttpServices, func(s HttpService) bool { return s.Name == desired.Name })
+
+		if idx < 0 {
+			plan = append(plan, PlanStep{Action: ActionCreate, Type: "http", Name: desired.Name, http: &desired, Disable: !entry.IsEnabled()})
+			continue
+		}
+
+		current := node.HttpServices[idx]
+		changes := diffHttp(current.HttpServiceParams, desired)
+		plan = append(plan, planExisting("http", current.ID, desired.Name, changes, current.Enabled, entry.IsEnabled(), &desired, nil))
+	}
+
+	for _, entry := range m.Tcp {
+		desired := entry.normalize()
+		idx := slices.IndexFunc(node.TcpServices, func(s TcpService) bool { return s.Name == desired.Name })
+
+		if idx < 0 {
+			plan = append(plan, PlanStep{Action: ActionCreate, Type: "tcp", Name: desired.Name, tcp: &desired, Disable: !entry.IsEnabled()})
+			continue
+		}
+
+		current := node.TcpServices[idx]
+		changes := diffTcp(current.TcpServiceParams, desired)
+		plan = append(plan, planExisting("tcp", current.ID, desired.Name, changes, current.Enabled, entry.IsEnabled(), nil, &desired))
+	}
+
+	if prune {
+		for _, svc := range node.HttpServices {
+			if svc.Enabled && !slices.ContainsFunc(m.Http, func(e HttpManifestEntry) bool { return e.Name == svc.Name }) {
+				plan = append(plan, PlanStep{Action: ActionDisable, Type: "http", Name: svc.Name, ID: svc.ID, Changes: []string{"not in manifest"}})
+			}
+		}
+		for _, svc := range node.TcpServices {
+			if svc.Enabled && !slices.ContainsFunc(m.Tcp, func(e TcpManifestEntry) bool { return e.Name == svc.Name }) {
+				plan = append(plan, PlanStep{Action: ActionDisable, Type: "tcp", Name: svc.Name, ID: svc.ID, Changes: []string{"not in manifest"}})
+			}
+		}
+	}
+
+	return plan
+}
+
+func planExisting(serviceType string, id int64, name string, changes []string, enabled, wantEnabled bool, http *HttpServiceParams, tcp *TcpServiceParams) PlanStep {
+	step := PlanStep{Type: serviceType, Name: name, ID: id, Changes: changes, http: http, tcp: tcp}
+
+	switch {
+	case len(changes) > 0:
+		// Exposing again updates the service and enables it.
+		step.Action = ActionUpdate
+		step.Disable = !wantEnabled
+	case enabled == wantEnabled:
+		step.Action = ActionUnchanged
+	case wantEnabled:
+		step.Action = ActionEnable
+	default:
+		step.Action = ActionDisable
+	}
+
+	return step
+}
+
+// Apply runs the plan steps in order and stops at the first failure. onStep,
+// if set, is called before each step that changes something.
+func (c *Client) Apply(ctx context.Context, plan []PlanStep, onStep func(PlanStep)) error {
+	for _, step := range plan {
+		if step.Action == ActionUnchanged {
+			continue
+		}
+		if onStep != nil {
+			onStep(step)
+		}
+		if err := c.applyStep(ctx, step); err != nil {
+			return fmt.Errorf("%s %s service %q: %w", step.Action, step.Type, step.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) applyStep(ctx context.Context, step PlanStep) error {
+	id := step.ID
+
+	if step.Type == "gateway" {
+		// The API updates a single subnet per call.
+		if len(step.networks) != 1 {
+			return fmt.Errorf("only one gateway network can be set through the API, the manifest has %d", len(step.networks))
+		}
+		network := step.networks[0]
+		if network.Interface == "" {
+			network.Interface = utils.GetDefaultInterfaceName()
+		}
+		_, err := c.UpdateGatewaySubnet(ctx, network)
+		return err
+	}
+
+	switch step.Action {
+	case ActionCreate, ActionUpdate:
+		if step.http != nil {
+			svc, err := c.ExposeHTTP(ctx, *step.http)
+			if err != nil {
+				return err
+			}
+			id = svc.ID
+		} else {
+			svc, err := c.ExposeTCP(ctx, *step.tcp)
+			if err != nil {
+				return err
+			}
+			id = svc.ID
+		}
+		if !step.Disable {
+			return nil
+		}
+	case ActionEnable:
+		_, err := c.EnableService(ctx, EnableRequest{ServiceType: step.Type, ID: strconv.FormatInt(id, 10), Ttl: step.ttl()})
+		return err
+	}
+
+	_, err := c.DisableService(ctx, step.Type, strconv.FormatInt(id, 10))
+	return err
+}
+
+// ttl is the ttl the manifest gives the service of the step.
+func (step PlanStep) ttl() string {
+	switch {
+	case step.http != nil:
+		return step.http.Ttl
+	case step.tcp != nil:
+		return step.tcp.Ttl
+	}
+	return ""
+}
+
+// ApplyManifest reconciles the configured node with manifest. With dryRun
+// set it only computes the plan.
+func ApplyManifest(ctx context.Context, manifest Manifest, prune bool, dryRun bool, onStep func(PlanStep)) ([]PlanStep, error) {
+	client := DefaultClient()
+
+	node, err := client.GetNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := manifest.Plan(node, prune)
+	if dryRun {
+		return plan, nil
+	}
+
+	return plan, client.Apply(ctx, plan, onStep)
+}
+
+func PrintPlan(plan []PlanStep) {
+	if len(plan) == 0 {
+		utils.Terminal().Println("Manifest is empty, nothing to do")
+		return
+	}
+
+	rows := make([][]string, 0, len(plan))
+	for _, step := range plan {
+		action := step.Action
+		if step.Disable {
+			action += "+disable"
+		}
+		rows = append(rows, []string{action, step.Type, step.Name, dash(strings.Join(step.Changes, ", "))})
+	}
+
+	utils.Terminal().Table([]string{"ACTION", "TYPE", "NAME", "CHANGES"}, rows)
+}
+
+func diffHttp(current, desired HttpServiceParams) []string {
+	var changes []string
+
+	changes = diffField(changes, "domain", current.Domain, desired.Domain)
+	changes = diffField(changes, "pathLocation", current.PathLocation, desired.PathLocation)
+	changes = diffField(changes, "backendHost", current.BackendHost, desired.BackendHost)
+	changes = diffField(changes, "backendProto", current.BackendProto, desired.BackendProto)
+	changes = diffField(changes, "backendPort", strconv.Itoa(current.BackendPort), strconv.Itoa(desired.BackendPort))
+	changes = diffList(changes, "allowedIps", current.AllowedIps, desired.AllowedIps)
+	changes = diffList(changes, "blockedIps", current.BlockedIps, desired.BlockedIps)
+	changes = diffField(changes, "ttl", comparableTtl(current.Ttl), desired.Ttl)
+
+	return changes
+}
+
+func diffTcp(current, desired TcpServiceParams) []string {
+	var changes []string
+
+	changes = diffField(changes, "domain", current.Domain, desired.Domain)
+	changes = diffField(changes, "proto", current.Proto, desired.Proto)
+	changes = diffField(changes, "backendHost", current.BackendHost, desired.BackendHost)
+	changes = diffField(changes, "backendPort", strconv.Itoa(current.BackendPort), strconv.Itoa(desired.BackendPort))
+	changes = diffField(changes, "ssl", strconv.FormatBool(current.Ssl), strconv.FormatBool(desired.Ssl))
+	// The public port is assigned by the server unless the manifest asks
+	// for a specific one.
+	if desired.Port != 0 {
+		changes = diffField(changes, "port", strconv.Itoa(current.Port), strconv.Itoa(desired.Port))
+	}
+	changes = diffList(changes, "allowedIps", current.AllowedIps, desired.AllowedIps)
+	changes = diffList(changes, "blockedIps", current.BlockedIps, desired.BlockedIps)
+	changes = diffField(changes, "ttl", comparableTtl(current.Ttl), desired.Ttl)
+
+	return changes
+}
+
+func diffNetworks(current, desired []GatewayNetwork) []string {
+	format := func(networks []GatewayNetwork) []string {
+		out := make([]string, 0, len(networks))
+		for _, n := range networks {
+			if n.Interface == "" {
+				out = append(out, n.Subnet)
+			} else {
+				out = append(out, n.Interface+": "+n.Subnet)
+			}
+		}
+		return out
+	}
+
+	// An interface left out of the manifest matches any interface.
+	if len(current) == len(desired) && !slices.ContainsFunc(desired, func(d GatewayNetwork) bool {
+		return !slices.ContainsFunc(current, func(c GatewayNetwork) bool {
+			return c.Subnet == d.Subnet && (d.Interface == "" || c.Interface == d.Interface)
+		})
+	}) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("gatewayNetworks: [%s] -> [%s]", strings.Join(format(current), ", "), strings.Join(format(desired), ", "))}
+}
+
+func diffField(changes []string, field, current, desired string) []string {
+	if current == desired {
+		return changes
+	}
+	return append(changes, fmt.Sprintf("%s: %s -> %s", field, dash(current), dash(desired)))
+}
+
+// diffList compares IP lists regardless of order.
+func diffList(changes []string, field string, current, desired []string) []string {
+	a := slices.Sorted(slices.Values(current))
+	b := slices.Sorted(slices.Values(desired))
+	if slices.Equal(a, b) {
+		return changes
+	}
+	return append(changes, fmt.Sprintf("%s: [%s] -> [%s]", field, strings.Join(current, ", "), strings.Join(desired, ", ")))
+}
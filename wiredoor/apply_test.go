@@ -0,0 +1,146 @@
+package wiredoor_test
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+func enabled(v bool) *bool { return &v }
+
+func httpEntry(name string, port int, ttl string, on bool) wiredoor.HttpManifestEntry {
+	return wiredoor.HttpManifestEntry{
+		HttpServiceParams: wiredoor.HttpServiceParams{Name: name, Domain: name + ".example.com", BackendPort: port, Ttl: ttl},
+		Enabled:           enabled(on),
+	}
+}
+
+// seedServices exposes web (enabled), old (disabled, 1h ttl) and db (TCP,
+// enabled) on the node of client.
+func seedServices(t *testing.T, client *wiredoor.Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, entry := range []wiredoor.HttpManifestEntry{httpEntry("web", 3000, "", true), httpEntry("old", 4000, "1h", true)} {
+		params := entry.HttpServiceParams
+		params.PathLocation, params.BackendProto = "/", "http"
+		if _, err := client.ExposeHTTP(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.ExposeTCP(ctx, wiredoor.TcpServiceParams{Name: "db", Proto: "tcp", BackendPort: 5432}); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := client.ResolveService(ctx, "http", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DisableService(ctx, "http", strconv.FormatInt(old.ID(), 10)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest wiredoor.Manifest
+		prune    bool
+		want     []string // "<action> <type> <name>" of the plan steps
+	}{
+		{
+			name:     "create",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("new", 5000, "", true)}},
+			want:     []string{"create http new"},
+		},
+		{
+			name:     "create disabled",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("new", 5000, "", false)}},
+			want:     []string{"create http new"},
+		},
+		{
+			name:     "unchanged",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("web", 3000, "", true)}},
+			want:     []string{"unchanged http web"},
+		},
+		{
+			name:     "update",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("web", 3001, "", true)}},
+			want:     []string{"update http web"},
+		},
+		{
+			name:     "enable keeps the ttl",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("old", 4000, "60m", true)}},
+			want:     []string{"enable http old"},
+		},
+		{
+			name:     "disable",
+			manifest: wiredoor.Manifest{Http: []wiredoor.HttpManifestEntry{httpEntry("web", 3000, "", false)}},
+			want:     []string{"disable http web"},
+		},
+		{
+			name:     "without prune",
+			manifest: wiredoor.Manifest{},
+			want:     nil,
+		},
+		{
+			name:     "prune",
+			manifest: wiredoor.Manifest{},
+			prune:    true,
+			want:     []string{"disable http web", "disable tcp db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newFakeClient(t)
+			seedServices(t, client)
+
+			node, err := client.GetNode(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plan := tt.manifest.Plan(node, tt.prune)
+			var got []string
+			for _, step := range plan {
+				got = append(got, step.Action+" "+step.Type+" "+step.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("plan = %q, want %q", got, tt.want)
+			}
+
+			if err := client.Apply(ctx, plan, nil); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+
+			node, err = client.GetNode(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range tt.manifest.Plan(node, tt.prune) {
+				if step.Action != wiredoor.ActionUnchanged {
+					t.Errorf("after Apply: %s %s %s %v, want no change", step.Action, step.Type, step.Name, step.Changes)
+				}
+			}
+
+			for _, entry := range tt.manifest.Http {
+				if entry.Ttl == "" || !entry.IsEnabled() {
+					continue
+				}
+				svc, err := client.ResolveService(ctx, "http", entry.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if svc.ExpiresAt() == nil || time.Until(*svc.ExpiresAt()) < 59*time.Minute {
+					t.Errorf("%s expires at %v, want in about an hour", entry.Name, svc.ExpiresAt())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,560 @@
+package wiredoor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/version"
+)
+
+const (
+	defaultRequestTimeout = 20 * time.Second
+	defaultRetries        = 3
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+type ClientOptions struct {
+	BaseURL    string // Wiredoor server URL, e.g. https://wiredoor.example.com
+	Token      string // node or admin bearer token
+	PathPrefix string // API base path on the server (default: /)
+
+	TLS   TLSOptions // ignored when HTTPClient is set
+	Proxy string     // proxy URL, "direct" for none; default: HTTPS_PROXY/NO_PROXY. Ignored when HTTPClient is set
+
+	Timeout time.Duration // per attempt, default 20s
+	Retries int           // extra attempts for idempotent calls, default 3; negative disables
+
+	HTTPClient *http.Client // default: shared transport
+	Logger     *slog.Logger // default: discard
+}
+
+// Client is a Wiredoor API client. Its methods only return data and errors;
+// they never write to the terminal, so it can be embedded in other tools.
+type Client struct {
+	baseURL    string
+	token      string
+	pathPrefix string
+
+	timeout time.Duration
+	retries int
+
+	http   *http.Client
+	logger *slog.Logger
+
+	// err holds a construction error (e.g. unreadable CA bundle); it is
+	// returned by every request so NewClient stays infallible.
+	err error
+
+	// tokenErr tells why DefaultClient could not resolve the node token.
+	// WithToken clears it.
+	tokenErr error
+}
+
+func NewClient(opts ClientOptions) *Client {
+	var err error
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient, err = sharedHTTPClient(opts.TLS, opts.Proxy)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	retries := opts.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	if retries < 0 {
+		retries = 0
+	}
+
+	return &Client{
+		baseURL:    opts.BaseURL,
+		token:      opts.Token,
+		pathPrefix: opts.PathPrefix,
+		timeout:    timeout,
+		retries:    retries,
+		http:       httpClient,
+		logger:     logger,
+		err:        err,
+	}
+}
+
+type transportKey struct {
+	tls   TLSOptions
+	proxy string
+}
+
+// sharedClient is a cached client and the modification times of the TLS
+// files it was built from.
+type sharedClient struct {
+	client  *http.Client
+	modTime [3]time.Time
+}
+
+var (
+	httpClientsMu sync.Mutex
+	httpClients   = map[transportKey]sharedClient{}
+)
+
+// tlsFilesModTime returns the modification times of the CA bundle and
+// client key pair of opts; missing files give the zero time.
+func tlsFilesModTime(opts TLSOptions) [3]time.Time {
+	var times [3]time.Time
+	for i, file := range []string{opts.CAFile, opts.ClientCert, opts.ClientKey} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// sharedHTTPClient returns the process wide client for a TLS and proxy
+// setup, so connections are reused across API calls. The client is
+// rebuilt when the CA bundle or client key pair changes on disk, so a
+// long-running daemon picks up rotated certificates.
+func sharedHTTPClient(opts TLSOptions, proxy string) (*http.Client, error) {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+
+	key := transportKey{tls: opts, proxy: proxy}
+	modTime := tlsFilesModTime(opts)
+	cached, ok := httpClients[key]
+	if ok && cached.modTime == modTime {
+		return cached.client, nil
+	}
+
+	tlsConfig, err := NewTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyFn, err := proxyFunc(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timeouts are applied per attempt through the request context.
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           proxyFn,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	if ok {
+		cached.client.CloseIdleConnections()
+	}
+	httpClients[key] = sharedClient{client: client, modTime: modTime}
+
+	return client, nil
+}
+
+// WithToken returns a copy of the client authenticating with token.
+func (c *Client) WithToken(token string) *Client {
+	clone := *c
+	clone.token = token
+	clone.tokenErr = nil
+	return &clone
+}
+
+// WithBaseURL returns a copy of the client targeting another server.
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	clone := *c
+	clone.baseURL = baseURL
+	return &clone
+}
+
+func (c *Client) AdminLogin(ctx context.Context, username string, password string) (string, error) {
+	body, _ := json.Marshal(AdminCredentials{
+		Username: username,
+		Password: password,
+	})
+
+	response := adminLoginResponse{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "POST", Path: "/auth/login", Body: body}, &response); err != nil {
+		return "", err
+	}
+
+	if response.Token == "" {
+		return "", errors.New("authentication failed")
+	}
+
+	return response.Token, nil
+}
+
+// ConfigureNode registers a new node. The client must hold an admin token.
+func (c *Client) ConfigureNode(ctx context.Context, node NodeParams) (Node, error) {
+	body, _ := json.Marshal(node)
+
+	created := Node{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "POST", Path: "/nodes", Body: body}, &created); err != nil {
+		return Node{}, err
+	}
+
+	if created.Token == "" {
+		return Node{}, errors.New("unable to retrieve node configuration")
+	}
+
+	return created, nil
+}
+
+func (c *Client) GetNode(ctx context.Context) (NodeInfo, error) {
+	node := NodeInfo{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "GET", Path: "/cli/node"}, &node); err != nil {
+		return NodeInfo{}, err
+	}
+	node.normalize()
+
+	return node, nil
+}
+
+func (c *Client) GetServices(ctx context.Context) ([]HttpService, error) {
+	services := []HttpService{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "GET", Path: "/cli/services/http"}, &services); err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+func (c *Client) GetTcpServices(ctx context.Context) ([]TcpService, error) {
+	services := []TcpService{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "GET", Path: "/cli/services/tcp"}, &services); err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// GetNodeConfig returns the node's WireGuard configuration file contents.
+func (c *Client) GetNodeConfig(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, apiRequest{Method: "GET", Path: "/cli/config"})
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(strings.Trim(string(resp), "\""), "\\n", "\n"), nil
+}
+
+func (c *Client) GetApiConfig(ctx context.Context) (ApiConfig, error) {
+	config := ApiConfig{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "GET", Path: "/config", Timeout: 5}, &config); err != nil {
+		return ApiConfig{}, err
+	}
+
+	return config, nil
+}
+
+func (c *Client) GetNodeWGConfig(ctx context.Context) (WGConfig, error) {
+	config := WGConfig{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "GET", Path: "/cli/wgconfig"}, &config); err != nil {
+		return WGConfig{}, err
+	}
+
+	return config, nil
+}
+
+// RegenerateKeys rotates the node keys and token. The returned node holds
+// the new token; the client keeps using the old one. It is never retried:
+// a retry after a lost answer would rotate them again or fail with the old
+// token, and the new token would be lost.
+func (c *Client) RegenerateKeys(ctx context.Context) (Node, error) {
+	node := Node{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "PATCH", Path: "/cli/regenerate", NoRetry: true}, &node); err != nil {
+		return Node{}, err
+	}
+
+	if node.Token == "" {
+		return Node{}, errors.New("unable to regenerate keys. No token received")
+	}
+
+	return node, nil
+}
+
+func (c *Client) ExposeHTTP(ctx context.Context, service HttpServiceParams) (HttpService, error) {
+	body, _ := json.Marshal(service)
+
+	created := HttpService{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "POST", Path: "/cli/expose/http", Body: body}, &created); err != nil {
+		return HttpService{}, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) ExposeTCP(ctx context.Context, service TcpServiceParams) (TcpService, error) {
+	body, _ := json.Marshal(service)
+
+	created := TcpService{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "POST", Path: "/cli/expose/tcp", Body: body}, &created); err != nil {
+		return TcpService{}, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) EnableService(ctx context.Context, params EnableRequest) (Service, error) {
+	var body []byte
+
+	if params.Ttl != "" {
+		body, _ = json.Marshal(EnableParams{Ttl: params.Ttl})
+	}
+
+	resp, err := c.do(ctx, apiRequest{Method: "PATCH", Path: "/cli/services/" + params.ServiceType + "/" + params.ID + "/enable", Body: body})
+
+	if err != nil {
+		return Service{}, err
+	}
+
+	return decodeService(params.ServiceType, resp)
+}
+
+func (c *Client) DisableService(ctx context.Context, serviceType string, id string) (Service, error) {
+	resp, err := c.do(ctx, apiRequest{Method: "PATCH", Path: "/cli/services/" + serviceType + "/" + id + "/disable"})
+
+	if err != nil {
+		return Service{}, err
+	}
+
+	return decodeService(serviceType, resp)
+}
+
+func (c *Client) UpdateGatewaySubnet(ctx context.Context, network GatewayNetwork) (NodeInfo, error) {
+	body, _ := json.Marshal(UpdateGatewayParams{GatewayNetwork: network.Subnet, GatewayInterface: network.Interface})
+
+	node := NodeInfo{}
+
+	if err := c.doJSON(ctx, apiRequest{Method: "PATCH", Path: "/cli/node/gateway", Body: body}, &node); err != nil {
+		return NodeInfo{}, err
+	}
+	node.normalize()
+
+	return node, nil
+}
+
+func decodeService(serviceType string, data []byte) (Service, error) {
+	switch serviceType {
+	case "http":
+		service := HttpService{}
+		if err := json.Unmarshal(data, &service); err != nil {
+			return Service{}, fmt.Errorf("unable to decode HTTP service: %w", err)
+		}
+		return Service{Type: serviceType, Http: &service}, nil
+	case "tcp":
+		service := TcpService{}
+		if err := json.Unmarshal(data, &service); err != nil {
+			return Service{}, fmt.Errorf("unable to decode TCP service: %w", err)
+		}
+		return Service{Type: serviceType, Tcp: &service}, nil
+	default:
+		return Service{}, fmt.Errorf("invalid service type %q", serviceType)
+	}
+}
+
+func (c *Client) doJSON(ctx context.Context, request apiRequest, out any) error {
+	resp, err := c.do(ctx, request)
+
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(resp, out); err != nil {
+		return &APIError{Message: "unable to decode " + request.Path + " response", Err: err}
+	}
+
+	return nil
+}
+
+// do performs the request, retrying transport errors and 429/502/503/504
+// answers with exponential backoff and jitter. Only GET and PATCH calls are
+// retried after a failure; any method is retried on 429 since the server
+// rejected it before doing any work. Requests marked NoRetry are never
+// retried. Retry-After is honored on 429 and 503.
+func (c *Client) do(ctx context.Context, request apiRequest) ([]byte, error) {
+	if c.err != nil {
+		return nil, &APIError{Message: "invalid client configuration: " + c.err.Error(), Err: c.err}
+	}
+	if c.tokenErr != nil {
+		return nil, c.tokenErr
+	}
+
+	base, err := url.Parse(c.baseURL)
+
+	if err != nil || base.Host == "" {
+		return nil, &APIError{Message: "invalid server URL: " + c.baseURL, Err: err}
+	}
+
+	base.Path = path.Join(base.Path, path.Join(c.pathPrefix, "/api", request.Path))
+
+	idempotent := request.Method == http.MethodGet || request.Method == http.MethodPatch
+
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.attempt(ctx, base.String(), request)
+
+		if err == nil {
+			return body, nil
+		}
+
+		if request.NoRetry || attempt >= c.retries || ctx.Err() != nil || !retryable(err, idempotent) {
+			return nil, err
+		}
+
+		delay := backoff(attempt)
+		if retryAfter > 0 {
+			delay = min(retryAfter, time.Minute)
+		}
+
+		c.logger.Debug("retrying api request", "method", request.Method, "path", request.Path, "attempt", attempt+1, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, &APIError{Err: ctx.Err()}
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, endpoint string, request apiRequest) ([]byte, time.Duration, error) {
+	timeout := c.timeout
+
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, request.Method, endpoint, bytes.NewReader(request.Body))
+
+	if err != nil {
+		return nil, 0, &APIError{Message: "unable to perform request", Err: err}
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wiredoor-cli/"+version.Version)
+
+	if c.token != "" {
+		req.Header.Add("Authorization", "Bearer "+c.token)
+	}
+
+	c.logger.Debug("api request", "method", request.Method, "path", request.Path)
+
+	resp, err := c.http.Do(req)
+
+	if err != nil {
+		c.logger.Debug("api request failed", "method", request.Method, "path", request.Path, "error", err)
+		if isHandshakeRejected(err) {
+			return nil, 0, &APIError{Message: "TLS handshake rejected by server: client certificate missing or not accepted", Err: fmt.Errorf("%w: %v", ErrClientCertificateRejected, err)}
+		}
+		return nil, 0, &APIError{Err: err}
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+
+	c.logger.Debug("api response", "method", request.Method, "path", request.Path, "status", resp.StatusCode)
+
+	if err != nil {
+		return nil, 0, &APIError{StatusCode: resp.StatusCode, Message: "unable to read body response", Err: err}
+	}
+
+	if apiErr := responseError(resp.StatusCode, bodyBytes); apiErr != nil {
+		var retryAfter time.Duration
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+		return nil, retryAfter, apiErr
+	}
+
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "application/json") {
+		return nil, 0, &APIError{StatusCode: resp.StatusCode, Message: "unexpected response format: " + resp.Header.Get("Content-Type")}
+	}
+
+	return bodyBytes, 0, nil
+}
+
+func retryable(err error, idempotent bool) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	case 0:
+		if apiErr.Err == nil || IsCertificateError(err) || IsClientCertificateRejected(err) {
+			return false
+		}
+		return idempotent && !errors.Is(err, context.Canceled)
+	}
+
+	return false
+}
+
+// backoff returns the delay before retry number attempt+1: exponential from
+// retryBaseDelay, capped at retryMaxDelay, with jitter in [d/2, d).
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay in seconds
+// or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+package wiredoor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+	"github.com/wiredoor/wiredoor-cli/wiredoor/fake"
+)
+
+// newFakeClient returns a client of the node registered on a fresh fake
+// server.
+func newFakeClient(t *testing.T) *wiredoor.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(fake.NewServer(fake.Options{}))
+	t.Cleanup(srv.Close)
+
+	return wiredoor.NewClient(wiredoor.ClientOptions{BaseURL: srv.URL, Token: fake.DefaultNodeToken, Retries: -1})
+}
+
+// The answer to a regeneration can be lost after the server rotated the
+// keys; retrying would rotate them again and lose the new token.
+func TestRegenerateKeysIsNotRetried(t *testing.T) {
+	server := fake.NewServer(fake.Options{})
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/cli/regenerate" {
+			server.ServeHTTP(w, r)
+			return
+		}
+		if calls.Add(1) == 1 {
+			server.ServeHTTP(httptest.NewRecorder(), r)
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := wiredoor.NewClient(wiredoor.ClientOptions{BaseURL: srv.URL, Token: fake.DefaultNodeToken, Retries: 3})
+
+	if _, err := client.RegenerateKeys(context.Background()); !wiredoor.IsServerError(err) {
+		t.Fatalf("RegenerateKeys error = %v, want a server error", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("regenerate called %d times, want 1", n)
+	}
+	if _, ok := server.Node(fake.DefaultNodeToken); ok {
+		t.Error("the fake server did not rotate the token")
+	}
+}
@@ -4,50 +4,136 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/wiredoor/wiredoor-cli/utils"
 	"gopkg.in/ini.v1"
 )
 
-// !TODO search for dependencies on var configFile
-var configFile = GetConfigLocation()
+// configFile is the config file in use: the --config flag (see
+// SetConfigLocation), then WIREDOOR_CONFIG, then the system location.
+var configFile = defaultConfigLocation()
+
+// configErrorOnce reports an unreadable config file once per run.
+var configErrorOnce sync.Once
+
+// Settings are resolved in this order:
+//
+//  1. command flags (e.g. 'wiredoor connect --url')
+//  2. WIREDOOR_<SECTION>_<KEY> environment variables (see EnvName)
+//  3. the config file
+//  4. built-in defaults
+//
+// Environment variables are only read, never saved to the config file.
 
 var defaultConfig = map[string]map[string]string{
 	"server": {
-		"url":   "",
-		"token": "",
-		"path":  "",
+		"url":              "",
+		"token":            "",
+		"token_command":    "",
+		"token_encryption": "",
+		"path":             "",
+		"ca_file":          "",
+		"pin_sha256":       "",
+		"insecure":         "false",
+		"client_cert":      "",
+		"client_key":       "",
+		"interface":        "",
 	},
 	"client": {
 		"keepalive": "25",
+		"retries":   "3",
+		"timeout":   "20",
+		"proxy":     "",
 	},
 	"daemon": {
 		"enabled": "false",
 	},
+	"health": {
+		"enabled":      "false",
+		"interval":     "30",
+		"timeout":      "5",
+		"failures":     "3",
+		"auto_disable": "false",
+	},
 }
 
 type ServerConfig struct {
-	Url   string
-	Token string
-	Path  string
+	Url             string
+	Token           string // as stored; see ResolveToken
+	TokenCommand    string
+	TokenEncryption string
+	Path            string
+	CAFile          string
+	PinSHA256       string
+	Insecure        string
+	ClientCert      string
+	ClientKey       string
+}
+
+// TLSOptions returns the TLS settings of the server. Relative file paths
+// are resolved against the directory holding config.ini.
+func (s ServerConfig) TLSOptions() TLSOptions {
+	return TLSOptions{
+		CAFile:     configRelativePath(s.CAFile),
+		PinSHA256:  s.PinSHA256,
+		Insecure:   parseBool(s.Insecure),
+		ClientCert: configRelativePath(s.ClientCert),
+		ClientKey:  configRelativePath(s.ClientKey),
+	}
 }
 
 type ClientConfig struct {
 	KeepAlive string
+	Retries   string
+	Timeout   string
+	Proxy     string
 }
 
 type DaemonConfig struct {
 	Enabled string
 }
 
+type HealthConfig struct {
+	Enabled     string
+	Interval    string
+	Timeout     string
+	Failures    string
+	AutoDisable string
+}
+
 type Config struct {
 	Server ServerConfig
 	Client ClientConfig
 	Daemon DaemonConfig
+	Health HealthConfig
 }
 
+// GetConfigLocation returns the path of the config file in use.
 func GetConfigLocation() string {
+	return configFile
+}
+
+// SetConfigLocation makes path the config file used by this process. An
+// empty path keeps the current one.
+func SetConfigLocation(path string) {
+	if path != "" {
+		configFile = path
+		migrationTried.Store(false)
+	}
+}
+
+func defaultConfigLocation() string {
+	if env := strings.TrimSpace(os.Getenv("WIREDOOR_CONFIG")); env != "" {
+		return env
+	}
+	return SystemConfigLocation()
+}
+
+// SystemConfigLocation returns the default config file of this OS.
+func SystemConfigLocation() string {
 	currentOS := runtime.GOOS
 	switch currentOS {
 	case "windows":
@@ -60,28 +146,66 @@ func GetConfigLocation() string {
 }
 
 func SaveServerConfig(server string, token string) error {
-	cfg, err := getIniFile()
+	return updateConfig(func(cfg *ini.File) error {
+		section := serverSection(cfg)
+		section.Key("url").SetValue(server)
+		return storeToken(section, token)
+	})
+}
 
-	if err != nil {
-		utils.Terminal().Errorf("Unable to get configuration file: %v", err)
-		return err
-	}
+// SaveServerTrust stores the server URL together with the SPKI pin used to
+// authenticate it. An empty pin removes a previously stored one.
+func SaveServerTrust(server string, pin string) error {
+	return updateConfig(func(cfg *ini.File) error {
+		section := serverSection(cfg)
+		section.Key("url").SetValue(server)
+		section.Key("pin_sha256").SetValue(pin)
+		return nil
+	})
+}
 
-	cfg.Section("server").Key("url").SetValue(server)
-	cfg.Section("server").Key("token").SetValue(token)
+// SaveClientCertificate stores the mutual TLS key pair used for API calls.
+func SaveClientCertificate(cert string, key string) error {
+	return updateConfig(func(cfg *ini.File) error {
+		section := serverSection(cfg)
+		section.Key("client_cert").SetValue(cert)
+		section.Key("client_key").SetValue(key)
+		return nil
+	})
+}
 
-	return cfg.SaveTo(configFile)
+func GetServerConfig() ServerConfig {
+	return getConfig().Server
 }
 
-func SaveDaemonConfig(useDaemon bool) {
-	cfg, err := getIniFile()
+func GetClientConfig() ClientConfig {
+	return getConfig().Client
+}
 
+func GetHealthConfig() HealthConfig {
+	return getConfig().Health
+}
+
+// GetHealthPath returns the path probed with an HTTP GET for the HTTP
+// service name, set in a [health.<name>] section. Empty means the backend
+// is only checked for accepting connections.
+func GetHealthPath(name string) string {
+	cfg, err := getIniFile()
 	if err != nil {
-		utils.Terminal().Errorf("Unable to get configuration file: %v", err)
+		cfg = ini.Empty()
 	}
-	cfg.Section("daemon").Key("enabled").SetValue(boolToString(useDaemon))
+	return configValue(cfg.Section("health."+name), "health."+name, "path")
+}
 
-	cfg.SaveTo(configFile)
+func SaveDaemonConfig(useDaemon bool) {
+	err := updateConfig(func(cfg *ini.File) error {
+		cfg.Section("daemon").Key("enabled").SetValue(boolToString(useDaemon))
+		return nil
+	})
+
+	if err != nil {
+		utils.Terminal().Errorf("Unable to save configuration file: %v", err)
+	}
 }
 
 func IsDaemonEnabled() bool {
@@ -93,31 +217,84 @@ func IsDaemonEnabled() bool {
 func IsServerConfigSet() bool {
 	config := getConfig()
 
-	return config.Server.Url != "" && config.Server.Token != ""
+	return config.Server.Url != "" && (config.Server.Token != "" || config.Server.TokenCommand != "")
 }
 
 func getConfig() Config {
 	cfg, err := getIniFile()
 
+	// Environment overrides and defaults still apply to an unreadable file.
 	if err != nil {
-		utils.Terminal().Errorf("Unable to get configuration file: %v", err)
+		configErrorOnce.Do(func() {
+			utils.Terminal().Errorf("Unable to get configuration file: %v", err)
+		})
+		cfg = ini.Empty()
+	}
+
+	server := func(key string) string {
+		return configValue(serverSection(cfg), "server", key)
+	}
+	value := func(section string, key string) string {
+		return configValue(cfg.Section(section), section, key)
 	}
 
 	return Config{
 		Server: ServerConfig{
-			Url:   cfg.Section("server").Key("url").String(),
-			Token: cfg.Section("server").Key("token").String(),
-			Path:  cfg.Section("server").Key("path").String(),
+			Url:             server("url"),
+			Token:           server("token"),
+			TokenCommand:    server("token_command"),
+			TokenEncryption: server("token_encryption"),
+			Path:            server("path"),
+			CAFile:          server("ca_file"),
+			PinSHA256:       server("pin_sha256"),
+			Insecure:        server("insecure"),
+			ClientCert:      server("client_cert"),
+			ClientKey:       server("client_key"),
 		},
 		Client: ClientConfig{
-			KeepAlive: cfg.Section("client").Key("keepalive").String(),
+			KeepAlive: value("client", "keepalive"),
+			Retries:   value("client", "retries"),
+			Timeout:   value("client", "timeout"),
+			Proxy:     value("client", "proxy"),
 		},
 		Daemon: DaemonConfig{
-			Enabled: cfg.Section("daemon").Key("enabled").String(),
+			Enabled: value("daemon", "enabled"),
+		},
+		Health: HealthConfig{
+			Enabled:     value("health", "enabled"),
+			Interval:    value("health", "interval"),
+			Timeout:     value("health", "timeout"),
+			Failures:    value("health", "failures"),
+			AutoDisable: value("health", "auto_disable"),
 		},
 	}
 }
 
+// EnvName returns the environment variable that overrides key of section,
+// e.g. WIREDOOR_SERVER_URL or WIREDOOR_HEALTH_MY_WEBSITE_PATH for path in
+// [health.my-website]. Server keys use the SERVER prefix whatever the profile.
+func EnvName(section string, key string) string {
+	name := strings.ToUpper("wiredoor_" + section + "_" + key)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// configValue returns key of section, or the value of its environment
+// variable when set and not empty. name is the section name used for the
+// variable.
+func configValue(section *ini.Section, name string, key string) string {
+	if env := os.Getenv(EnvName(name, key)); env != "" {
+		return env
+	}
+	return section.Key(key).String()
+}
+
+// getIniFile loads the config file for reading. Changes must be made with
+// updateConfig, which holds the config lock.
 func getIniFile() (*ini.File, error) {
 	cfg, err := ini.Load(configFile)
 
@@ -125,30 +302,36 @@ func getIniFile() (*ini.File, error) {
 		if os.IsNotExist(err) {
 			return createDefaultConfigFile()
 		}
+		return cfg, err
 	}
 
-	return cfg, err
+	warnUnsafeConfig()
+
+	return migrateOnLoad(cfg), nil
 }
 
 func createDefaultConfigFile() (*ini.File, error) {
-	dir := filepath.Dir(configFile)
-
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := updateConfig(func(cfg *ini.File) error { return nil }); err != nil {
 		return nil, err
 	}
 
-	cfg := ini.Empty()
+	return ini.Load(configFile)
+}
 
-	for section, keys := range defaultConfig {
-		sec, _ := cfg.NewSection(section)
-		for key, value := range keys {
-			sec.NewKey(key, value)
-		}
+func configRelativePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
 	}
+	return filepath.Join(filepath.Dir(configFile), path)
+}
 
-	err := cfg.SaveTo(configFile)
-
-	return cfg, err
+// parseInt returns def when val is empty or not a number.
+func parseInt(val string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return def
+	}
+	return n
 }
 
 func parseBool(val string) bool {
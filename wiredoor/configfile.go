@@ -0,0 +1,144 @@
+package wiredoor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"gopkg.in/ini.v1"
+)
+
+// configMode is the mode of config.ini, which holds the node token.
+const configMode = 0o600
+
+var checkPermissionsOnce sync.Once
+
+// configLocked is set while this process holds the config lock, so that
+// reading the config meanwhile does not try to migrate it and lock again.
+var configLocked atomic.Bool
+
+// updateConfig runs a read-modify-write cycle on the config file while
+// holding an exclusive lock, so the daemon and an interactive command never
+// overwrite each other's changes. The file is replaced atomically.
+func updateConfig(update func(cfg *ini.File) error) error {
+	_, _, err := writeConfig(update)
+	return err
+}
+
+// writeConfig is updateConfig, also migrating an outdated file after
+// backing it up. It returns the migration changes and the backup path.
+func writeConfig(update func(cfg *ini.File) error) ([]ConfigChange, string, error) {
+	unlock, err := lockConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	cfg, err := ini.Load(configFile)
+	if os.IsNotExist(err) {
+		cfg, err = newDefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	backup := ""
+	version := configVersion(cfg)
+	changes := migrateConfig(cfg)
+	if len(changes) > 0 {
+		if backup, err = backupConfig(version); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := update(cfg); err != nil {
+		return nil, "", err
+	}
+	if err := saveConfig(cfg); err != nil {
+		return nil, "", err
+	}
+	return changes, backup, nil
+}
+
+// saveConfig writes cfg to a temporary file with mode 0600 next to the config
+// file and renames it over the config file, so readers never see a partial
+// write.
+func saveConfig(cfg *ini.File) error {
+	dir := filepath.Dir(configFile)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(configFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(configMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := cfg.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), configFile)
+}
+
+func newDefaultConfig() *ini.File {
+	cfg := ini.Empty()
+
+	for section, keys := range defaultConfig {
+		sec, _ := cfg.NewSection(section)
+		for key, value := range keys {
+			sec.NewKey(key, value)
+		}
+	}
+	cfg.Section("meta").Key("version").SetValue(strconv.Itoa(ConfigVersion))
+
+	return cfg
+}
+
+// lockConfig takes the advisory lock guarding config changes. It locks a
+// separate file, as renaming replaces config.ini itself.
+func lockConfig() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(configFile+".lock", os.O_CREATE|os.O_RDWR, configMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	configLocked.Store(true)
+
+	return func() {
+		configLocked.Store(false)
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// warnUnsafeConfig warns once per run when the config file can be read or
+// changed by other users.
+func warnUnsafeConfig() {
+	checkPermissionsOnce.Do(func() {
+		if problem := configPermissionProblem(configFile); problem != "" {
+			utils.Terminal().Warnf("%s", problem)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package wiredoor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// configPermissionProblem describes why path is unsafe to hold a token: it
+// is accessible to group or others, or owned by another user than root or
+// the current one.
+func configPermissionProblem(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		return fmt.Sprintf("%s is accessible by other users (mode %04o); run 'chmod 600 %s'", path, mode, path)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if uid := int(stat.Uid); uid != 0 && uid != os.Geteuid() {
+			return fmt.Sprintf("%s is owned by another user (uid %d); it should belong to root", path, uid)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package wiredoor
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func lockFile(f *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &overlapped)
+}
+
+func unlockFile(f *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &overlapped)
+}
+
+// configPermissionProblem is not checked on Windows, where access is
+// governed by the ACLs of %PROGRAMDATA%\wiredoor.
+func configPermissionProblem(path string) string {
+	return ""
+}
@@ -0,0 +1,418 @@
+package wiredoor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// ConfigEntry is a setting with its effective value and where the value
+// comes from: "env (WIREDOOR_...)", "file" or "default".
+type ConfigEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// configKey describes a known config key for 'wiredoor config'.
+type configKey struct {
+	validate func(string) error
+	secret   bool
+	boolean  bool
+}
+
+var boolKey = configKey{validate: validateBool, boolean: true}
+
+// configSections lists the known keys of each section, in display order.
+// Server keys also apply to [profile <name>] sections, and path to
+// [health.<name>] sections.
+var configSections = []string{"server", "client", "daemon", "health", "context"}
+
+var configKeys = map[string]map[string]configKey{
+	"server": {
+		"url":              {validate: validateServerURL},
+		"token":            {secret: true},
+		"token_command":    {},
+		"token_encryption": {validate: ValidateTokenEncryption},
+		"path":             {validate: validateAbsolutePath},
+		"ca_file":          {},
+		"pin_sha256":       {validate: validatePin},
+		"insecure":         boolKey,
+		"client_cert":      {},
+		"client_key":       {},
+		"interface":        {validate: optional(ValidateInterfaceName)},
+	},
+	"client": {
+		"keepalive": {validate: intRange(0, 65535)},
+		"retries":   {validate: intRange(0, 10)},
+		"timeout":   {validate: intRange(0, 3600)},
+		"proxy":     {validate: validateProxy},
+	},
+	"daemon": {
+		"enabled": boolKey,
+	},
+	"health": {
+		"enabled":      boolKey,
+		"interval":     {validate: intRange(1, 86400)},
+		"timeout":      {validate: intRange(1, 300)},
+		"failures":     {validate: intRange(1, 100)},
+		"auto_disable": boolKey,
+	},
+	"context": {
+		"current": {validate: optional(ValidateProfileName)},
+	},
+	"meta": {
+		"version": {validate: intRange(0, ConfigVersion)},
+	},
+}
+
+var healthServiceKeys = map[string]configKey{
+	"path": {validate: validateAbsolutePath},
+}
+
+// parseConfigKey splits a dotted key such as client.keepalive or
+// health.my-website.path into its section and key. server.* keys address
+// the profile in use.
+func parseConfigKey(name string) (section string, key string, spec configKey, err error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", configKey{}, fmt.Errorf("invalid key %q: use <section>.<key>, e.g. client.keepalive", name)
+	}
+	section, key = name[:i], name[i+1:]
+
+	keys, ok := sectionKeys(section)
+	if !ok {
+		return "", "", configKey{}, fmt.Errorf("unknown section %q", section)
+	}
+	spec, ok = keys[key]
+	if !ok {
+		return "", "", configKey{}, unknownKeyError(section, key, keys)
+	}
+	return section, key, spec, nil
+}
+
+func sectionKeys(section string) (map[string]configKey, bool) {
+	if keys, ok := configKeys[section]; ok {
+		return keys, true
+	}
+	if name, ok := strings.CutPrefix(section, "health."); ok && name != "" {
+		return healthServiceKeys, true
+	}
+	return nil, false
+}
+
+func unknownKeyError(section string, key string, keys map[string]configKey) error {
+	best, distance := "", 3
+	for known := range keys {
+		if d := editDistance(key, known); d < distance {
+			best, distance = known, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown key %q in [%s] (did you mean %q?)", key, section, best)
+	}
+	return fmt.Errorf("unknown key %q in [%s]", key, section)
+}
+
+func fileSection(cfg *ini.File, section string) *ini.Section {
+	if section == "server" {
+		return serverSection(cfg)
+	}
+	return cfg.Section(section)
+}
+
+// GetConfigValue returns the effective value of a dotted key. Secrets are
+// masked unless reveal is set, in which case the token is decrypted or
+// fetched from token_command.
+func GetConfigValue(name string, reveal bool) (ConfigEntry, error) {
+	section, key, spec, err := parseConfigKey(name)
+	if err != nil {
+		return ConfigEntry{}, err
+	}
+
+	cfg, err := getIniFile()
+	if err != nil {
+		return ConfigEntry{}, err
+	}
+
+	entry := configEntry(cfg, section, key, spec)
+	if spec.secret && reveal {
+		token, err := getConfig().Server.ResolveToken()
+		if err != nil {
+			return ConfigEntry{}, err
+		}
+		entry.Value = token
+	}
+	return entry, nil
+}
+
+func configEntry(cfg *ini.File, section string, key string, spec configKey) ConfigEntry {
+	entry := ConfigEntry{Key: section + "." + key}
+
+	stored := fileSection(cfg, section)
+	switch {
+	case os.Getenv(EnvName(section, key)) != "":
+		entry.Value = os.Getenv(EnvName(section, key))
+		entry.Source = "env (" + EnvName(section, key) + ")"
+	case stored.HasKey(key) && stored.Key(key).String() != "":
+		entry.Value = stored.Key(key).String()
+		entry.Source = "file"
+	default:
+		entry.Value = defaultConfig[section][key]
+		entry.Source = "default"
+	}
+
+	if spec.secret {
+		entry.Value = maskSecret(entry.Value)
+	}
+	return entry
+}
+
+func maskSecret(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case strings.HasPrefix(value, encryptedTokenPrefix):
+		return "(encrypted)"
+	default:
+		return "********"
+	}
+}
+
+// ListConfig returns the effective value of every known key, with the
+// server keys of the profile in use and the [health.<name>] sections found
+// in the file.
+func ListConfig() ([]ConfigEntry, error) {
+	cfg, err := getIniFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ConfigEntry
+	addSection := func(section string, keys map[string]configKey) {
+		for _, key := range sortedKeys(keys) {
+			entries = append(entries, configEntry(cfg, section, key, keys[key]))
+		}
+	}
+
+	for _, section := range configSections {
+		addSection(section, configKeys[section])
+	}
+	for _, s := range cfg.Sections() {
+		if strings.HasPrefix(s.Name(), "health.") {
+			addSection(s.Name(), healthServiceKeys)
+		}
+	}
+	return entries, nil
+}
+
+// SetConfigValue validates value and stores it under a dotted key. The
+// token is stored as token_command and token_encryption require.
+func SetConfigValue(name string, value string) error {
+	section, key, spec, err := parseConfigKey(name)
+	if err != nil {
+		return err
+	}
+	if spec.validate != nil {
+		if err := spec.validate(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+
+	if section == "context" && key == "current" {
+		return UseProfile(value)
+	}
+
+	return updateConfig(func(cfg *ini.File) error {
+		stored := fileSection(cfg, section)
+		if section == "server" && key == "token" {
+			return storeToken(stored, value)
+		}
+		stored.Key(key).SetValue(value)
+		return nil
+	})
+}
+
+// UnsetConfigValue removes a dotted key from the config file, so its
+// default applies again.
+func UnsetConfigValue(name string) error {
+	section, key, _, err := parseConfigKey(name)
+	if err != nil {
+		return err
+	}
+
+	return updateConfig(func(cfg *ini.File) error {
+		fileSection(cfg, section).DeleteKey(key)
+		return nil
+	})
+}
+
+// ValidateConfig checks the config file and the WIREDOOR_<SECTION>_<KEY>
+// environment variables: unknown sections and keys, and invalid values.
+func ValidateConfig() []error {
+	cfg, err := ini.Load(configFile)
+	if err != nil {
+		return []error{err}
+	}
+
+	var problems []error
+	check := func(where string, spec configKey, value string) {
+		if spec.validate == nil || value == "" {
+			return
+		}
+		if err := spec.validate(value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", where, err))
+		}
+	}
+
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		keys, ok := sectionKeys(name)
+
+		switch {
+		case name == ini.DefaultSection:
+			if len(section.Keys()) > 0 {
+				problems = append(problems, errors.New("keys outside of any section"))
+			}
+			continue
+		case strings.HasPrefix(name, profileSectionPrefix):
+			if err := ValidateProfileName(strings.TrimPrefix(name, profileSectionPrefix)); err != nil {
+				problems = append(problems, fmt.Errorf("[%s]: %w", name, err))
+			}
+			keys, ok = configKeys["server"], true
+		case !ok:
+			problems = append(problems, fmt.Errorf("unknown section [%s]", name))
+			continue
+		}
+
+		for _, key := range section.Keys() {
+			spec, known := keys[key.Name()]
+			if !known {
+				problems = append(problems, unknownKeyError(name, key.Name(), keys))
+				continue
+			}
+			check(fmt.Sprintf("[%s] %s", name, key.Name()), spec, key.String())
+		}
+	}
+
+	for _, section := range configSections {
+		for key, spec := range configKeys[section] {
+			env := EnvName(section, key)
+			check(env, spec, os.Getenv(env))
+		}
+	}
+
+	if current := cfg.Section("context").Key("current").String(); current != "" && current != DefaultProfile && !cfg.HasSection(profileSectionName(current)) {
+		problems = append(problems, fmt.Errorf("[context] current: profile %q does not exist", current))
+	}
+
+	return problems
+}
+
+func sortedKeys(keys map[string]configKey) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func optional(validate func(string) error) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+		return validate(value)
+	}
+}
+
+func validateServerURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q: use http(s)://host[:port]", value)
+	}
+	return nil
+}
+
+func validateAbsolutePath(value string) error {
+	if value != "" && !strings.HasPrefix(value, "/") {
+		return fmt.Errorf("%q must start with /", value)
+	}
+	return nil
+}
+
+func validatePin(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := NormalizePin(value)
+	return err
+}
+
+func validateProxy(value string) error {
+	if value == "" || value == ProxyDirect {
+		return nil
+	}
+	_, err := ParseProxyURL(value)
+	return err
+}
+
+func validateBool(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "1", "0", "true", "false", "yes", "no", "on", "off":
+		return nil
+	default:
+		return fmt.Errorf("%q is not a boolean: use true or false", value)
+	}
+}
+
+func intRange(min int, max int) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		if n < min || n > max {
+			return fmt.Errorf("%d is out of range (%d-%d)", n, min, max)
+		}
+		return nil
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b, used to
+// suggest a known key for a typo.
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
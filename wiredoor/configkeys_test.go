@@ -0,0 +1,191 @@
+package wiredoor
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+const currentConfig = "[meta]\nversion = 2\n"
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr string // substring of the error, empty when the value is stored
+	}{
+		{key: "client.keepalive", value: "15"},
+		{key: "client.keepalive", value: "70000", wantErr: "out of range (0-65535)"},
+		{key: "client.retries", value: "many", wantErr: "is not a number"},
+		{key: "daemon.enabled", value: "true"},
+		{key: "daemon.enabled", value: "maybe", wantErr: "is not a boolean"},
+		{key: "server.url", value: "https://wiredoor.example.com"},
+		{key: "server.url", value: "ftp://wiredoor.example.com", wantErr: "invalid URL"},
+		{key: "server.path", value: "api", wantErr: "must start with /"},
+		{key: "server.token_encryption", value: "rot13", wantErr: "invalid token encryption"},
+		{key: "client.proxy", value: "socks5h://proxy.example.com:1080"},
+		{key: "health.my-website.path", value: "/healthz"},
+		{key: "health.my-website.path", value: "healthz", wantErr: "must start with /"},
+		{key: "keepalive", value: "15", wantErr: "use <section>.<key>"},
+		{key: "client.", value: "15", wantErr: "use <section>.<key>"},
+		{key: "proxy.url", value: "http://proxy", wantErr: `unknown section "proxy"`},
+		{key: "client.keepaliv", value: "15", wantErr: `did you mean "keepalive"?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			path := useConfigFile(t, currentConfig)
+
+			err := SetConfigValue(tt.key, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SetConfigValue error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetConfigValue: %v", err)
+			}
+
+			cfg, err := ini.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := strings.LastIndex(tt.key, ".")
+			if got := cfg.Section(tt.key[:i]).Key(tt.key[i+1:]).String(); got != tt.value {
+				t.Errorf("stored %s = %q, want %q", tt.key, got, tt.value)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"keepalive", "keepalive", 0},
+		{"keepaliv", "keepalive", 1},
+		{"retry", "retries", 3},
+		{"tiemout", "timeout", 2},
+		{"", "url", 3},
+		{"url", "", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownKeySuggestion(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "tiemout", want: `unknown key "tiemout" in [client] (did you mean "timeout"?)`},
+		{key: "retrie", want: `unknown key "retrie" in [client] (did you mean "retries"?)`},
+		{key: "compression", want: `unknown key "compression" in [client]`},
+	}
+
+	for _, tt := range tests {
+		if got := unknownKeyError("client", tt.key, configKeys["client"]).Error(); got != tt.want {
+			t.Errorf("unknownKeyError(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestGetConfigValueSource(t *testing.T) {
+	useConfigFile(t, currentConfig+"[client]\ntimeout = 30\nretries =\n[server]\ntoken = secret\n")
+	t.Setenv(EnvName("client", "keepalive"), "10")
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{key: "client.timeout", wantValue: "30", wantSource: "file"},
+		{key: "client.retries", wantValue: "3", wantSource: "default"},
+		{key: "daemon.enabled", wantValue: "false", wantSource: "default"},
+		{key: "client.keepalive", wantValue: "10", wantSource: "env (WIREDOOR_CLIENT_KEEPALIVE)"},
+		{key: "server.token", wantValue: "********", wantSource: "file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			entry, err := GetConfigValue(tt.key, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Value != tt.wantValue || entry.Source != tt.wantSource {
+				t.Errorf("GetConfigValue(%q) = %q from %q, want %q from %q", tt.key, entry.Value, entry.Source, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		want    []string // substrings of the problems, in order
+	}{
+		{
+			name:    "valid",
+			content: "[server]\nurl = https://wiredoor.example.com\n[client]\nkeepalive = 25\n[profile staging]\nurl = http://localhost:3000\n[health.web]\npath = /health\n",
+		},
+		{
+			name:    "unknown section",
+			content: "[proxy]\nurl = http://proxy\n",
+			want:    []string{"unknown section [proxy]"},
+		},
+		{
+			name:    "unknown key with suggestion",
+			content: "[daemon]\nenable = true\n",
+			want:    []string{`unknown key "enable" in [daemon] (did you mean "enabled"?)`},
+		},
+		{
+			name:    "invalid values",
+			content: "[client]\nkeepalive = -1\n[profile staging]\nurl = staging\n",
+			want:    []string{"[client] keepalive: -1 is out of range", "[profile staging] url: invalid URL"},
+		},
+		{
+			name:    "keys outside of any section",
+			content: "url = https://wiredoor.example.com\n",
+			want:    []string{"keys outside of any section"},
+		},
+		{
+			name:    "missing current profile",
+			content: "[context]\ncurrent = staging\n",
+			want:    []string{`profile "staging" does not exist`},
+		},
+		{
+			name: "invalid environment variable",
+			env:  map[string]string{"WIREDOOR_HEALTH_INTERVAL": "0"},
+			want: []string{"WIREDOOR_HEALTH_INTERVAL: 0 is out of range"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigFile(t, tt.content+currentConfig)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			problems := ValidateConfig()
+			if len(problems) != len(tt.want) {
+				t.Fatalf("ValidateConfig() = %v, want %d problems", problems, len(tt.want))
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), tt.want[i]) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, problem, tt.want[i])
+				}
+			}
+		})
+	}
+}
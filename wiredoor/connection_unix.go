@@ -6,6 +6,8 @@ package wiredoor
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -16,9 +18,23 @@ import (
 	"github.com/wiredoor/wiredoor-cli/utils"
 )
 
-var configFilename = utils.TunnelName + ".conf"
 var wireguardPath = "/etc/wireguard/"
-var interfaceNameFile = "/var/run/wiredoor/" + utils.TunnelName + "-interface"
+var runtimePath = "/var/run/wiredoor/"
+
+func configFilename() string {
+	return TunnelName() + ".conf"
+}
+
+// interfaceNameFile holds the system interface of the tunnel, written on
+// connect.
+func interfaceNameFile() string {
+	return runtimePath + TunnelName() + "-interface"
+}
+
+// profileFile holds the profile that brought the tunnel up.
+func profileFile() string {
+	return runtimePath + TunnelName() + "-profile"
+}
 
 type ConnectionConfig struct {
 	URL       string
@@ -32,9 +48,14 @@ type ConnectionConfig struct {
 // 	Peers      []wgtypes.PeerConfig
 // }
 
-func Connect(connection ConnectionConfig) {
+// Connect brings up the tunnel of the active profile and shows its status.
+func Connect(ctx context.Context, connection ConnectionConfig) error {
 	ensureRoot()
 
+	if err := ValidateInterfaceName(TunnelName()); err != nil {
+		return err
+	}
+
 	if connection.URL != "" && connection.Token != "" {
 		SaveServerConfig(connection.URL, connection.Token)
 	}
@@ -46,7 +67,11 @@ func Connect(connection ConnectionConfig) {
 	utils.Terminal().StartProgress("Connecting...")
 	defer utils.Terminal().StopProgress()
 
-	node := GetNode()
+	node, err := GetNode(ctx)
+
+	if err != nil {
+		return fmt.Errorf("unable to retrieve node information: %w", err)
+	}
 
 	if node.ID > 0 {
 		nodeType := "node"
@@ -58,10 +83,13 @@ func Connect(connection ConnectionConfig) {
 		utils.Terminal().UpdateProgress("Connecting " + nodeType + " " + node.Name)
 
 		// Using wg-quick
-		manualLinuxConnect()
+		if err := manualLinuxConnect(ctx); err != nil {
+			return err
+		}
 
-		Status()
+		Status(ctx)
 	}
+	return nil
 }
 
 func RestartTunnel() {
@@ -81,21 +109,21 @@ func ensureRoot() {
 	}
 }
 
-func manualLinuxConnect() {
+func manualLinuxConnect(ctx context.Context) error {
 	if err := os.MkdirAll(wireguardPath, 0o700); err != nil {
-		utils.Terminal().Errorf("Error creating WireGuard directory: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("error creating WireGuard directory: %w", err)
 	}
 
-	config := GetNodeConfig()
+	config, err := GetNodeConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve WireGuard configuration: %w", err)
+	}
 
-	err := os.WriteFile(wireguardPath+configFilename, []byte(config), 0600)
+	err = os.WriteFile(wireguardPath+configFilename(), []byte(config), 0600)
 	if err != nil {
-		utils.Terminal().Errorf("Error writing WireGuard configuration file: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("error writing WireGuard configuration file: %w", err)
 	}
-	//take care of spaces
-	up := exec.Command("bash", "-c", "wg-quick up "+utils.TunnelName) // wg-quick down wg0 > /dev/null &2>1
+	up := exec.Command("wg-quick", "up", TunnelName())
 
 	if IsDaemonEnabled() {
 		RestartService()
@@ -103,31 +131,37 @@ func manualLinuxConnect() {
 	}
 
 	if err := up.Run(); err != nil {
-		utils.Terminal().Errorf("Unable to connect to tunnel, please review your user permissions or if you are inside container ensure that you have added the capability NET_ADMIN")
-		os.Exit(1)
+		return errors.New("unable to connect to tunnel, please review your user permissions or if you are inside container ensure that you have added the capability NET_ADMIN")
 	}
 
 	iface, err := parseInterfaceName()
 	if err != nil || iface == "" {
-		utils.Terminal().Errorf("Unable to determine the interface name after connecting")
-		os.Exit(1)
+		return errors.New("unable to determine the interface name after connecting")
 	}
 
-	if err := os.MkdirAll("/var/run/wiredoor", 0o755); err != nil {
-		utils.Terminal().Errorf("Error creating Wiredoor runtime directory: %v", err)
-		os.Exit(1)
+	if err := os.MkdirAll(runtimePath, 0o755); err != nil {
+		return fmt.Errorf("error creating Wiredoor runtime directory: %w", err)
 	}
 
-	err = os.WriteFile(interfaceNameFile, []byte(iface), 0644)
+	err = os.WriteFile(interfaceNameFile(), []byte(iface), 0644)
 	if err != nil {
-		utils.Terminal().Errorf("Error writing Wiredoor interface file: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("error writing Wiredoor interface file: %w", err)
 	}
+
+	err = os.WriteFile(profileFile(), []byte(ActiveProfile()), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing Wiredoor profile file: %w", err)
+	}
+	return nil
 }
 
 func manualLinuxRestart() {
-	restart := exec.Command("bash", "-c", "wg-quick down "+utils.TunnelName+" && wg-quick up "+utils.TunnelName) // wg-quick down wg0 > /dev/null &2>1
-	if err := restart.Run(); err != nil {
+	name := TunnelName()
+	err := exec.Command("wg-quick", "down", name).Run()
+	if err == nil {
+		err = exec.Command("wg-quick", "up", name).Run()
+	}
+	if err != nil {
 		utils.Terminal().Errorf("Unable to restart the tunnel, please review your user permissions or if you are inside container ensure that you have added the capability NET_ADMIN")
 		os.Exit(1)
 	}
@@ -138,7 +172,7 @@ func manualLinuxDisconnect() {
 	if ExistWireguardConfigFile() {
 		utils.Terminal().StartProgress("Disconnecting...")
 		defer utils.Terminal().StopProgress()
-		down := exec.Command("bash", "-c", "wg-quick down "+utils.TunnelName) // wg-quick down wg0 > /dev/null &2>1
+		down := exec.Command("wg-quick", "down", TunnelName())
 
 		if IsDaemonEnabled() {
 			StopService()
@@ -151,22 +185,27 @@ func manualLinuxDisconnect() {
 		utils.Terminal().FinalizeProgress()
 		utils.Terminal().Printf("Disconnected successfully.")
 
-		_ = os.Remove(wireguardPath + configFilename)
-		_ = os.Remove(interfaceNameFile)
+		_ = os.Remove(wireguardPath + configFilename())
+		_ = os.Remove(interfaceNameFile())
+		_ = os.Remove(profileFile())
 	} else {
 		utils.Terminal().Printf("No active WireGuard configuration found. Already disconnected.")
 	}
 }
 
 func ExistWireguardConfigFile() bool {
-	_, err := os.Stat(wireguardPath + configFilename)
+	_, err := os.Stat(wireguardPath + configFilename())
 
 	return err == nil
 }
 
 func parseInterfaceName() (string, error) {
 	if runtime.GOOS == "linux" {
-		return utils.TunnelName, nil
+		return TunnelName(), nil
+	}
+	// wg-quick records the utun interface it picked on macOS.
+	if name, err := os.ReadFile("/var/run/wireguard/" + TunnelName() + ".name"); err == nil && len(name) > 0 {
+		return strings.TrimSpace(string(name)), nil
 	}
 	out, err := exec.Command("sudo", "wg", "show", "all", "dump").Output()
 	if err != nil {
@@ -195,13 +234,23 @@ func parseInterfaceName() (string, error) {
 }
 
 func getInterfaceName() string {
-	iface, err := os.ReadFile("/var/run/wiredoor/" + utils.TunnelName + "-interface")
+	iface, err := os.ReadFile(interfaceNameFile())
 	if err != nil || len(iface) == 0 {
 		return ""
 	}
 	return strings.TrimSpace(string(iface))
 }
 
+// tunnelOwner returns the profile that brought the tunnel up, or an empty
+// string when it is down or was brought up by an older version.
+func tunnelOwner() string {
+	owner, err := os.ReadFile(profileFile())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(owner))
+}
+
 func interfaceExists() bool {
 	ifaceName := getInterfaceName()
 	if ifaceName == "" {
@@ -4,6 +4,7 @@
 package wiredoor
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
@@ -16,9 +17,9 @@ import (
 	// "golang.org/x/sys/windows" //windows admin
 )
 
-// // var interfaceName = "wg0"
-// var TunnelName = "Wiredoor_Tunnel" //used to stop service
-var configFilename = utils.TunnelName + ".conf"
+func configFilename() string {
+	return TunnelName() + ".conf"
+}
 
 // system paths
 var wireguardConfigFolder = os.Getenv("PROGRAMDATA") + "\\wiredoor\\"
@@ -34,7 +35,7 @@ type ConnectionConfig struct {
 //		PrivateKey wgtypes.Key
 //		Peers      []wgtypes.PeerConfig
 //	}
-func ConnectApi(connection ConnectionConfig) error {
+func ConnectApi(ctx context.Context, connection ConnectionConfig) error {
 	// ensureRoot()
 
 	if connection.URL != "" && connection.Token != "" {
@@ -47,7 +48,11 @@ func ConnectApi(connection ConnectionConfig) error {
 		SaveDaemonConfig(connection.UseDaemon)
 	}
 
-	node := GetNode()
+	node, err := GetNode(ctx)
+
+	if err != nil {
+		return fmt.Errorf("unable to retrieve node information: %w", err)
+	}
 
 	if node.ID > 0 {
 		nodeType := "node"
@@ -59,7 +64,7 @@ func ConnectApi(connection ConnectionConfig) error {
 		slog.Info(fmt.Sprintf("Connecting %s %s...", nodeType, node.Name))
 
 		// Using wireguard service
-		if err := manualWindowsConnect(); err != nil {
+		if err := manualWindowsConnect(ctx); err != nil {
 			return err
 		}
 		slog.Info("Waiting for connection (5 secs max)")
@@ -77,7 +82,7 @@ func ConnectApi(connection ConnectionConfig) error {
 			return fmt.Errorf("wireguard interface not detected")
 		}
 		// if ExistWireguardConfigFile() {
-		// 	_ = os.Remove(wireguardConfigFolder + configFilename)
+		// 	_ = os.Remove(wireguardConfigFolder + configFilename())
 		// }
 		return nil
 	} else {
@@ -85,21 +90,13 @@ func ConnectApi(connection ConnectionConfig) error {
 	}
 }
 
-func Connect(connection ConnectionConfig) {
-	if err := ConnectApi(connection); err != nil {
-		utils.Terminal().Printf("Connection error: %v", err)
-		//!!!! DO NOT KILL SERVICE
-		//===================================
-		isSvc, err := svc.IsWindowsService()
-		if err != nil {
-			return
-		}
-		if isSvc {
-			return
-		}
-		//====================================
-		os.Exit(1)
+// Connect brings up the tunnel. Errors are returned rather than ending the
+// process, which may be the Windows service.
+func Connect(ctx context.Context, connection ConnectionConfig) error {
+	if err := ConnectApi(ctx, connection); err != nil {
+		return fmt.Errorf("connection error: %w", err)
 	}
+	return nil
 }
 
 func RestartTunnel() {
@@ -140,35 +137,38 @@ func ensureRoot() {
 	// }
 }
 
-func manualWindowsConnect() error {
-	config := GetNodeConfig()
+func manualWindowsConnect(ctx context.Context) error {
+	config, err := GetNodeConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve WireGuard configuration: %w", err)
+	}
 
 	//cleanup
-	exists, err := utils.ServiceExists("WireGuardTunnel$" + utils.TunnelName)
+	exists, err := utils.ServiceExists("WireGuardTunnel$" + TunnelName())
 	if err != nil {
 		slog.Warn("Unable to determine if tunnel service exists, assuming true", "error", err)
 		exists = true
 	}
 	if exists {
 		//sc stop WireGuardTunnel$wg0
-		stop := exec.Command("sc", "stop", "WireGuardTunnel$"+utils.TunnelName)
+		stop := exec.Command("sc", "stop", "WireGuardTunnel$"+TunnelName())
 		if err := stop.Run(); err != nil {
 			slog.Error("Unable to stop tunnel service", "error", err)
 		}
 
 		//wireguard /uninstalltunnelservice wg0
-		down := exec.Command("wireguard", "/uninstalltunnelservice", utils.TunnelName)
+		down := exec.Command("wireguard", "/uninstalltunnelservice", TunnelName())
 		if err := down.Run(); err != nil {
 			slog.Error("Unable to disconnect wireguard tunnel", "error", err)
 		}
 	}
 
-	err = os.WriteFile(wireguardConfigFolder+configFilename, []byte(config), 0600)
+	err = os.WriteFile(wireguardConfigFolder+configFilename(), []byte(config), 0600)
 	if err != nil {
 		return fmt.Errorf("error on write cfg,%v", err)
 	}
 	//wireguard /installtunnelservice full_file_path
-	up := exec.Command("wireguard", "/installtunnelservice", wireguardConfigFolder+configFilename)
+	up := exec.Command("wireguard", "/installtunnelservice", wireguardConfigFolder+configFilename())
 
 	if err := up.Run(); err != nil {
 		return fmt.Errorf("unable to connect to tunnel")
@@ -176,13 +176,13 @@ func manualWindowsConnect() error {
 	/*
 		time.Sleep(250 * time.Millisecond)
 		//iniciar servicio
-		running, err := utils.ServiceRunning("WireGuardTunnel$" + utils.TunnelName)
+		running, err := utils.ServiceRunning("WireGuardTunnel$" + TunnelName())
 		if err != nil {
 			running = false
 		}
 		if !running {
 			//sc start WireGuardTunnel$wg0
-			if err := utils.StartService("WireGuardTunnel$" + utils.TunnelName); err != nil {
+			if err := utils.StartService("WireGuardTunnel$" + TunnelName()); err != nil {
 				return fmt.Errorf("unable to start tunnel service sunner, %v", err)
 			}
 		}
@@ -202,12 +202,12 @@ func manualWindowsConnect() error {
 
 func manualWindowsRestart() {
 	//sc stop WireGuardTunnel$wg0
-	stop := exec.Command("sc", "stop", "WireGuardTunnel$"+utils.TunnelName)
+	stop := exec.Command("sc", "stop", "WireGuardTunnel$"+TunnelName())
 	if err := stop.Run(); err != nil {
 		slog.Warn("Unable to stop tunnel service", "error", err)
 	}
 	//sc start WireGuardTunnel$wg0
-	start := exec.Command("sc", "start", "WireGuardTunnel$"+utils.TunnelName)
+	start := exec.Command("sc", "start", "WireGuardTunnel$"+TunnelName())
 	if err := start.Run(); err != nil {
 		slog.Warn("Unable to start tunnel service", "error", err)
 	}
@@ -217,20 +217,20 @@ func manualWindowsDisconnect() {
 
 	// log.Println("Disconecting...")
 
-	exists, err := utils.ServiceExists("WireGuardTunnel$" + utils.TunnelName)
+	exists, err := utils.ServiceExists("WireGuardTunnel$" + TunnelName())
 	if err != nil {
 		slog.Warn("Unable to determine if tunnel service exists, assuming true", "error", err)
 		exists = true
 	}
 	if exists {
 		//sc stop WireGuardTunnel$wg0
-		stop := exec.Command("sc", "stop", "WireGuardTunnel$"+utils.TunnelName)
+		stop := exec.Command("sc", "stop", "WireGuardTunnel$"+TunnelName())
 		if err := stop.Run(); err != nil {
 			slog.Warn("Unable to stop tunnel service", "error", err)
 		}
 
 		//wireguard /uninstalltunnelservice wg0
-		down := exec.Command("wireguard", "/uninstalltunnelservice", utils.TunnelName)
+		down := exec.Command("wireguard", "/uninstalltunnelservice", TunnelName())
 		if err := down.Run(); err != nil {
 			slog.Error("Unable to disconnect wireguard tunnel: ", "error", err)
 		}
@@ -248,17 +248,22 @@ func manualWindowsDisconnect() {
 	// }
 
 	if ExistWireguardConfigFile() {
-		_ = os.Remove(wireguardConfigFolder + configFilename)
+		_ = os.Remove(wireguardConfigFolder + configFilename())
 	}
 }
 
 func getInterfaceName() string {
-	return utils.TunnelName
+	return TunnelName()
+}
+
+// tunnelOwner is not tracked on Windows, where the service runs one tunnel.
+func tunnelOwner() string {
+	return ""
 }
 
 func ExistWireguardConfigFile() bool {
-	// log.Printf("Wireguard cfg: %s", wireguardConfigFolder+configFilename)
-	_, err := os.Stat(wireguardConfigFolder + configFilename)
+	// log.Printf("Wireguard cfg: %s", wireguardConfigFolder+configFilename())
+	_, err := os.Stat(wireguardConfigFolder + configFilename())
 	return err == nil
 }
 
@@ -276,7 +281,7 @@ func interfaceExists() bool {
 
 	if interfaces, err := net.Interfaces(); err == nil {
 		for i := 0; i < len(interfaces); i++ {
-			if interfaces[i].Name == utils.TunnelName /*&& (interfaces[i].Flags&net.FlagUp != 0) */ {
+			if interfaces[i].Name == TunnelName() /*&& (interfaces[i].Flags&net.FlagUp != 0) */ {
 				return true
 			}
 		}
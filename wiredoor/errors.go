@@ -0,0 +1,139 @@
+package wiredoor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrClientCertificateRejected is wrapped by API errors caused by the server
+// refusing the client certificate (or the lack of one) during mutual TLS.
+var ErrClientCertificateRejected = errors.New("client certificate rejected by server")
+
+// ErrServiceNotFound is wrapped by ResolveService when nothing matches.
+var ErrServiceNotFound = errors.New("service not found")
+
+// AmbiguousServiceError is returned by ResolveService when a name or ID
+// matches more than one service.
+type AmbiguousServiceError struct {
+	Ref        string
+	Candidates []Service
+}
+
+func (e *AmbiguousServiceError) Error() string {
+	candidates := make([]string, 0, len(e.Candidates))
+	for _, svc := range e.Candidates {
+		candidates = append(candidates, fmt.Sprintf("%s %d (%s)", svc.Type, svc.ID(), svc.Name()))
+	}
+	return fmt.Sprintf("%q matches several services: %s; specify the type and ID, e.g. '%s %d'", e.Ref, strings.Join(candidates, ", "), e.Candidates[0].Type, e.Candidates[0].ID())
+}
+
+// APIError describes a failed call to the Wiredoor API. StatusCode is zero
+// when the request never got an HTTP response (DNS, TLS, timeouts...), in
+// which case Err holds the underlying transport error.
+type APIError struct {
+	StatusCode       int
+	Message          string
+	ValidationErrors []ValidationError
+	Err              error
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.StatusCode == 0 && e.Err != nil:
+		return fmt.Sprintf("request failed: %v", e.Err)
+	case e.StatusCode == http.StatusUnprocessableEntity && len(e.ValidationErrors) > 0:
+		fields := make([]string, 0, len(e.ValidationErrors))
+		for _, v := range e.ValidationErrors {
+			fields = append(fields, v.Field+": "+v.Message)
+		}
+		msg := e.Message
+		if msg == "" {
+			msg = "validation failed"
+		}
+		return msg + " (" + strings.Join(fields, "; ") + ")"
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return fmt.Sprintf("unexpected response status %d", e.StatusCode)
+	}
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsUnauthorized reports whether err is an API error caused by a missing,
+// invalid or revoked token.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// IsValidationError reports whether err was rejected by the server because
+// of invalid request parameters.
+func IsValidationError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an API 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// IsServerError reports whether err is a 5xx answer from the Wiredoor server.
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	return false
+}
+
+// IsTransportError reports whether err happened before any HTTP response
+// was received (server down, DNS failure, timeout...).
+func IsTransportError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 0 && apiErr.Err != nil
+	}
+	return false
+}
+
+// IsCertificateError reports whether err was caused by the server
+// certificate failing verification or not matching the configured pin.
+func IsCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verification *tls.CertificateVerificationError
+	var pin *PinMismatchError
+
+	return errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &verification) ||
+		errors.As(err, &pin)
+}
+
+// IsClientCertificateRejected reports whether the server refused the mutual
+// TLS handshake.
+func IsClientCertificateRejected(err error) bool {
+	return errors.Is(err, ErrClientCertificateRejected)
+}
@@ -0,0 +1,579 @@
+// Package fake implements an in-memory Wiredoor server speaking the subset
+// of the API used by the CLI. It is meant for tests and offline CI runs:
+//
+//	srv := httptest.NewServer(fake.NewServer(fake.Options{}))
+//	client := wiredoor.NewClient(wiredoor.ClientOptions{BaseURL: srv.URL, Token: fake.DefaultNodeToken})
+package fake
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+const (
+	DefaultAdminUser     = "admin@example.com"
+	DefaultAdminPassword = "admin"
+	DefaultNodeToken     = "fake-node-token"
+	DefaultVPNHost       = "localhost"
+	DefaultTcpPortRange  = "32000-32099"
+)
+
+type Options struct {
+	AdminUser     string // default: DefaultAdminUser
+	AdminPassword string // default: DefaultAdminPassword
+
+	NodeToken string              // token of the pre-registered node, default: DefaultNodeToken
+	Node      wiredoor.NodeParams // pre-registered node, default name "fake-node"
+
+	VPNHost string // reported by /api/config and used in public URLs
+}
+
+// Server is an http.Handler holding all state in memory. It is safe for
+// concurrent use.
+type Server struct {
+	mu sync.Mutex
+
+	adminUser     string
+	adminPassword string
+	adminTokens   map[string]bool
+	vpnHost       string
+
+	nodes  map[string]*wiredoor.Node // by token
+	nextID int64
+
+	mux *http.ServeMux
+}
+
+func NewServer(opts Options) *Server {
+	s := &Server{
+		adminUser:     defString(opts.AdminUser, DefaultAdminUser),
+		adminPassword: defString(opts.AdminPassword, DefaultAdminPassword),
+		adminTokens:   map[string]bool{},
+		vpnHost:       defString(opts.VPNHost, DefaultVPNHost),
+		nodes:         map[string]*wiredoor.Node{},
+	}
+
+	params := opts.Node
+	params.Name = defString(params.Name, "fake-node")
+	s.addNode(defString(opts.NodeToken, DefaultNodeToken), params)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/login", s.login)
+	mux.HandleFunc("POST /api/nodes", s.admin(s.createNode))
+	mux.HandleFunc("GET /api/config", s.apiConfig)
+	mux.HandleFunc("GET /api/cli/node", s.node(s.getNode))
+	mux.HandleFunc("PATCH /api/cli/node/gateway", s.node(s.updateGateway))
+	mux.HandleFunc("GET /api/cli/config", s.node(s.wgQuickConfig))
+	mux.HandleFunc("GET /api/cli/wgconfig", s.node(s.wgConfig))
+	mux.HandleFunc("PATCH /api/cli/regenerate", s.node(s.regenerate))
+	mux.HandleFunc("GET /api/cli/services/http", s.node(s.listHttp))
+	mux.HandleFunc("GET /api/cli/services/tcp", s.node(s.listTcp))
+	mux.HandleFunc("POST /api/cli/expose/http", s.node(s.exposeHttp))
+	mux.HandleFunc("POST /api/cli/expose/tcp", s.node(s.exposeTcp))
+	mux.HandleFunc("PATCH /api/cli/services/{type}/{id}/{action}", s.node(s.toggleService))
+	s.mux = mux
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Node returns a copy of the node owning token.
+func (s *Server) Node(token string) (wiredoor.Node, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	node, ok := s.nodes[token]
+	if !ok {
+		return wiredoor.Node{}, false
+	}
+	return cloneNode(node), true
+}
+
+type nodeHandler func(w http.ResponseWriter, r *http.Request, node *wiredoor.Node)
+
+// node authenticates the bearer token as a node token and runs h with the
+// server lock held.
+func (s *Server) node(h nodeHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		node, ok := s.nodes[bearer(r)]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		expireServices(node, time.Now())
+		h(w, r, node)
+	}
+}
+
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if !s.adminTokens[bearer(r)] {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	credentials := wiredoor.AdminCredentials{}
+	if !decode(w, r, &credentials) {
+		return
+	}
+
+	if credentials.Username != s.adminUser || credentials.Password != s.adminPassword {
+		writeError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	token := newToken()
+
+	s.mu.Lock()
+	s.adminTokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "expiresIn": "1h"})
+}
+
+func (s *Server) createNode(w http.ResponseWriter, r *http.Request) {
+	params := wiredoor.NodeParams{}
+	if !decode(w, r, &params) {
+		return
+	}
+
+	if params.Name == "" {
+		writeValidation(w, wiredoor.ValidationError{Field: "name", Message: "\"name\" is required"})
+		return
+	}
+
+	node := s.addNode(newToken(), params)
+
+	writeJSON(w, http.StatusOK, node)
+}
+
+func (s *Server) apiConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, wiredoor.ApiConfig{
+		VPN_HOST:                s.vpnHost,
+		TCP_SERVICES_PORT_RANGE: DefaultTcpPortRange,
+	})
+}
+
+func (s *Server) getNode(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	writeJSON(w, http.StatusOK, wiredoor.NodeInfo{
+		Node:                     cloneNode(node),
+		ClientIp:                 node.Address,
+		LatestHandshakeTimestamp: time.Now().UnixMilli(),
+		Status:                   "online",
+	})
+}
+
+func (s *Server) updateGateway(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	params := wiredoor.UpdateGatewayParams{}
+	if !decode(w, r, &params) {
+		return
+	}
+
+	if !node.IsGateway {
+		writeError(w, http.StatusBadRequest, "Node is not a gateway")
+		return
+	}
+
+	node.GatewayNetwork = params.GatewayNetwork
+	node.GatewayNetworks = []wiredoor.GatewayNetwork{{Interface: params.GatewayInterface, Subnet: params.GatewayNetwork}}
+	node.UpdatedAt = time.Now()
+
+	writeJSON(w, http.StatusOK, wiredoor.NodeInfo{Node: cloneNode(node)})
+}
+
+func (s *Server) wgQuickConfig(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	config := fmt.Sprintf("[Interface]\nPrivateKey = %s\nAddress = %s/32\n\n[Peer]\nPublicKey = %s\nEndpoint = %s:51820\nAllowedIPs = 10.0.0.0/24\nPersistentKeepalive = 25\n",
+		fakeKey(node.Token), node.Address, fakeKey("server"), s.vpnHost)
+
+	writeJSON(w, http.StatusOK, config)
+}
+
+func (s *Server) wgConfig(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	writeJSON(w, http.StatusOK, wiredoor.WGConfig{
+		PrivateKey: fakeKey(node.Token),
+		Address:    node.Address,
+		Peer: wiredoor.PeerConfig{
+			PublicKey:                   fakeKey("server"),
+			Endpoint:                    wiredoor.PeerEndpoint{Url: s.vpnHost + ":51820", Host: s.vpnHost, Port: 51820},
+			PersistentKeepaliveInterval: 25,
+			AllowedIPs:                  []string{"10.0.0.0/24"},
+		},
+	})
+}
+
+func (s *Server) regenerate(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	delete(s.nodes, node.Token)
+	node.Token = newToken()
+	node.UpdatedAt = time.Now()
+	s.nodes[node.Token] = node
+
+	writeJSON(w, http.StatusOK, cloneNode(node))
+}
+
+func (s *Server) listHttp(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	writeJSON(w, http.StatusOK, node.HttpServices)
+}
+
+func (s *Server) listTcp(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	writeJSON(w, http.StatusOK, node.TcpServices)
+}
+
+// exposeHttp creates the service, or updates and re-enables the one with
+// the same name, like the real server does.
+func (s *Server) exposeHttp(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	params := wiredoor.HttpServiceParams{}
+	if !decode(w, r, &params) {
+		return
+	}
+
+	if errs := validateHttp(params, node.IsGateway); len(errs) > 0 {
+		writeValidation(w, errs...)
+		return
+	}
+
+	if params.PathLocation == "" {
+		params.PathLocation = "/"
+	}
+
+	now := time.Now()
+
+	for i := range node.HttpServices {
+		svc := &node.HttpServices[i]
+		if svc.Name == params.Name {
+			svc.HttpServiceParams = params
+			svc.Enabled = true
+			svc.PublicAccess = httpPublicAccess(params)
+			svc.ExpiresAt = expiry(params.Ttl, now)
+			svc.UpdatedAt = now
+			writeJSON(w, http.StatusOK, *svc)
+			return
+		}
+	}
+
+	s.nextID++
+	svc := wiredoor.HttpService{
+		ID:                s.nextID,
+		HttpServiceParams: params,
+		NodeId:            node.ID,
+		Enabled:           true,
+		PublicAccess:      httpPublicAccess(params),
+		ExpiresAt:         expiry(params.Ttl, now),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	node.HttpServices = append(node.HttpServices, svc)
+
+	writeJSON(w, http.StatusOK, svc)
+}
+
+func (s *Server) exposeTcp(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	params := wiredoor.TcpServiceParams{}
+	if !decode(w, r, &params) {
+		return
+	}
+
+	if params.Proto == "" {
+		params.Proto = "tcp"
+	}
+
+	if errs := validateTcp(params, node.IsGateway); len(errs) > 0 {
+		writeValidation(w, errs...)
+		return
+	}
+
+	now := time.Now()
+
+	for i := range node.TcpServices {
+		svc := &node.TcpServices[i]
+		if svc.Name == params.Name {
+			port := svc.Port
+			svc.TcpServiceParams = params
+			svc.Port = port
+			svc.Enabled = true
+			svc.ExpiresAt = expiry(params.Ttl, now)
+			svc.UpdatedAt = now
+			writeJSON(w, http.StatusOK, *svc)
+			return
+		}
+	}
+
+	s.nextID++
+	params.Port = 32000 + len(node.TcpServices)
+	svc := wiredoor.TcpService{
+		ID:               s.nextID,
+		TcpServiceParams: params,
+		NodeId:           node.ID,
+		Enabled:          true,
+		PublicAccess:     fmt.Sprintf("%s://%s:%d", params.Proto, s.vpnHost, params.Port),
+		ExpiresAt:        expiry(params.Ttl, now),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	node.TcpServices = append(node.TcpServices, svc)
+
+	writeJSON(w, http.StatusOK, svc)
+}
+
+func (s *Server) toggleService(w http.ResponseWriter, r *http.Request, node *wiredoor.Node) {
+	action := r.PathValue("action")
+	if action != "enable" && action != "disable" {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeValidation(w, wiredoor.ValidationError{Field: "id", Message: "\"id\" must be a number"})
+		return
+	}
+
+	params := wiredoor.EnableParams{}
+	if action == "enable" && r.ContentLength > 0 && !decode(w, r, &params) {
+		return
+	}
+
+	if !validTtl(params.Ttl) {
+		writeValidation(w, wiredoor.ValidationError{Field: "ttl", Message: "\"ttl\" must be a duration like 30m, 12h or 7d"})
+		return
+	}
+
+	enabled := action == "enable"
+	now := time.Now()
+
+	switch r.PathValue("type") {
+	case "http":
+		for i := range node.HttpServices {
+			svc := &node.HttpServices[i]
+			if svc.ID == id {
+				svc.Enabled = enabled
+				if enabled {
+					svc.Ttl = params.Ttl
+				}
+				svc.ExpiresAt = expiry(svc.Ttl, now)
+				if !enabled {
+					svc.ExpiresAt = nil
+				}
+				svc.UpdatedAt = now
+				writeJSON(w, http.StatusOK, *svc)
+				return
+			}
+		}
+	case "tcp":
+		for i := range node.TcpServices {
+			svc := &node.TcpServices[i]
+			if svc.ID == id {
+				svc.Enabled = enabled
+				if enabled {
+					svc.Ttl = params.Ttl
+				}
+				svc.ExpiresAt = expiry(svc.Ttl, now)
+				if !enabled {
+					svc.ExpiresAt = nil
+				}
+				svc.UpdatedAt = now
+				writeJSON(w, http.StatusOK, *svc)
+				return
+			}
+		}
+	default:
+		writeValidation(w, wiredoor.ValidationError{Field: "type", Message: "\"type\" must be one of [http, tcp]"})
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "Service not found")
+}
+
+// addNode must be called with the lock held (or before serving).
+func (s *Server) addNode(token string, params wiredoor.NodeParams) wiredoor.Node {
+	s.nextID++
+	now := time.Now()
+
+	if params.Address == "" {
+		params.Address = fmt.Sprintf("10.0.0.%d", len(s.nodes)+2)
+	}
+
+	node := &wiredoor.Node{
+		ID:           s.nextID,
+		NodeParams:   params,
+		WgInterface:  "wg0",
+		Enabled:      true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		HttpServices: []wiredoor.HttpService{},
+		TcpServices:  []wiredoor.TcpService{},
+		Token:        token,
+	}
+	if len(params.GatewayNetworks) > 0 {
+		node.GatewayNetwork = params.GatewayNetworks[0].Subnet
+	}
+	s.nodes[token] = node
+
+	return cloneNode(node)
+}
+
+func validateHttp(params wiredoor.HttpServiceParams, isGateway bool) []wiredoor.ValidationError {
+	var errs []wiredoor.ValidationError
+
+	if params.Name == "" {
+		errs = append(errs, wiredoor.ValidationError{Field: "name", Message: "\"name\" is required"})
+	}
+	if params.Domain == "" {
+		errs = append(errs, wiredoor.ValidationError{Field: "domain", Message: "\"domain\" is required"})
+	}
+	if params.BackendPort < 1 || params.BackendPort > 65535 {
+		errs = append(errs, wiredoor.ValidationError{Field: "backendPort", Message: "\"backendPort\" must be a valid port"})
+	}
+	if params.BackendProto != "http" && params.BackendProto != "https" {
+		errs = append(errs, wiredoor.ValidationError{Field: "backendProto", Message: "\"backendProto\" must be one of [http, https]"})
+	}
+	if isGateway && params.BackendHost == "" {
+		errs = append(errs, wiredoor.ValidationError{Field: "backendHost", Message: "\"backendHost\" is required for gateway nodes"})
+	}
+	if !validTtl(params.Ttl) {
+		errs = append(errs, wiredoor.ValidationError{Field: "ttl", Message: "\"ttl\" must be a duration like 30m, 12h or 7d"})
+	}
+
+	return errs
+}
+
+func validateTcp(params wiredoor.TcpServiceParams, isGateway bool) []wiredoor.ValidationError {
+	var errs []wiredoor.ValidationError
+
+	if params.Name == "" {
+		errs = append(errs, wiredoor.ValidationError{Field: "name", Message: "\"name\" is required"})
+	}
+	if params.BackendPort < 1 || params.BackendPort > 65535 {
+		errs = append(errs, wiredoor.ValidationError{Field: "backendPort", Message: "\"backendPort\" must be a valid port"})
+	}
+	if params.Proto != "tcp" && params.Proto != "udp" {
+		errs = append(errs, wiredoor.ValidationError{Field: "proto", Message: "\"proto\" must be one of [tcp, udp]"})
+	}
+	if isGateway && params.BackendHost == "" {
+		errs = append(errs, wiredoor.ValidationError{Field: "backendHost", Message: "\"backendHost\" is required for gateway nodes"})
+	}
+	if !validTtl(params.Ttl) {
+		errs = append(errs, wiredoor.ValidationError{Field: "ttl", Message: "\"ttl\" must be a duration like 30m, 12h or 7d"})
+	}
+
+	return errs
+}
+
+// expireServices disables services whose TTL has elapsed. The real server
+// does this with a scheduled job; here it happens lazily on each request.
+func expireServices(node *wiredoor.Node, now time.Time) {
+	for i := range node.HttpServices {
+		svc := &node.HttpServices[i]
+		if svc.ExpiresAt != nil && !svc.ExpiresAt.After(now) {
+			svc.Enabled = false
+			svc.ExpiresAt = nil
+		}
+	}
+	for i := range node.TcpServices {
+		svc := &node.TcpServices[i]
+		if svc.ExpiresAt != nil && !svc.ExpiresAt.After(now) {
+			svc.Enabled = false
+			svc.ExpiresAt = nil
+		}
+	}
+}
+
+// expiry returns when a service exposed at now with ttl expires, or nil for
+// no (or an invalid) ttl.
+func expiry(ttl string, now time.Time) *time.Time {
+	d, err := wiredoor.ParseTTL(ttl)
+	if err != nil || d <= 0 {
+		return nil
+	}
+	t := now.Add(d)
+	return &t
+}
+
+func validTtl(ttl string) bool {
+	_, err := wiredoor.ParseTTL(ttl)
+	return err == nil
+}
+
+func httpPublicAccess(params wiredoor.HttpServiceParams) string {
+	return "https://" + params.Domain + params.PathLocation
+}
+
+func cloneNode(node *wiredoor.Node) wiredoor.Node {
+	clone := *node
+	clone.HttpServices = append([]wiredoor.HttpService{}, node.HttpServices...)
+	clone.TcpServices = append([]wiredoor.TcpService{}, node.TcpServices...)
+	clone.GatewayNetworks = append([]wiredoor.GatewayNetwork(nil), node.GatewayNetworks...)
+	return clone
+}
+
+func bearer(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	body, _ := json.Marshal(v)
+	_, _ = w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, wiredoor.BadRequest{Status: strconv.Itoa(status), Message: message})
+}
+
+func writeValidation(w http.ResponseWriter, errs ...wiredoor.ValidationError) {
+	writeJSON(w, http.StatusUnprocessableEntity, wiredoor.UnprocessableRequest{
+		Message: "Validation error",
+		Errors:  wiredoor.ValidationErrors{Body: errs},
+	})
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// fakeKey returns a stable, syntactically valid WireGuard key for seed.
+func fakeKey(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func defString(v, d string) string {
+	if v == "" {
+		return d
+	}
+	return v
+}
@@ -0,0 +1,125 @@
+package fake_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor/fake"
+)
+
+func TestRoutes(t *testing.T) {
+	srv := httptest.NewServer(fake.NewServer(fake.Options{}))
+	defer srv.Close()
+
+	call := func(method, path, token, body string) (int, map[string]any) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var decoded any
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		object, _ := decoded.(map[string]any)
+		return resp.StatusCode, object
+	}
+
+	_, login := call("POST", "/api/auth/login", "", `{"username":"`+fake.DefaultAdminUser+`","password":"`+fake.DefaultAdminPassword+`"}`)
+	admin, _ := login["token"].(string)
+	if admin == "" {
+		t.Fatalf("login returned no token: %v", login)
+	}
+
+	node := fake.DefaultNodeToken
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"api config is public", "GET", "/api/config", "", "", http.StatusOK},
+		{"wrong password", "POST", "/api/auth/login", "", `{"username":"admin@example.com","password":"nope"}`, http.StatusUnauthorized},
+		{"invalid json", "POST", "/api/auth/login", "", `{`, http.StatusBadRequest},
+		{"node without token", "GET", "/api/cli/node", "", "", http.StatusUnauthorized},
+		{"node with admin token", "GET", "/api/cli/node", admin, "", http.StatusUnauthorized},
+		{"node", "GET", "/api/cli/node", node, "", http.StatusOK},
+		{"create node as node", "POST", "/api/nodes", node, `{"name":"other"}`, http.StatusUnauthorized},
+		{"create node without name", "POST", "/api/nodes", admin, `{}`, http.StatusUnprocessableEntity},
+		{"create node", "POST", "/api/nodes", admin, `{"name":"other"}`, http.StatusOK},
+		{"wg-quick config", "GET", "/api/cli/config", node, "", http.StatusOK},
+		{"wireguard config", "GET", "/api/cli/wgconfig", node, "", http.StatusOK},
+		{"gateway on a plain node", "PATCH", "/api/cli/node/gateway", node, `{"gatewayNetwork":"10.1.0.0/24"}`, http.StatusBadRequest},
+		{"expose http", "POST", "/api/cli/expose/http", node, `{"name":"web","domain":"web.example.com","backendProto":"http","backendPort":3000}`, http.StatusOK},
+		{"expose http without domain", "POST", "/api/cli/expose/http", node, `{"name":"web","backendPort":3000}`, http.StatusUnprocessableEntity},
+		{"expose tcp", "POST", "/api/cli/expose/tcp", node, `{"name":"db","backendPort":5432}`, http.StatusOK},
+		{"list http", "GET", "/api/cli/services/http", node, "", http.StatusOK},
+		{"list tcp", "GET", "/api/cli/services/tcp", node, "", http.StatusOK},
+		{"disable", "PATCH", "/api/cli/services/http/3/disable", node, "", http.StatusOK},
+		{"enable with ttl", "PATCH", "/api/cli/services/http/3/enable", node, `{"ttl":"1h"}`, http.StatusOK},
+		{"enable with invalid ttl", "PATCH", "/api/cli/services/http/3/enable", node, `{"ttl":"soon"}`, http.StatusUnprocessableEntity},
+		{"unknown action", "PATCH", "/api/cli/services/http/3/pause", node, "", http.StatusNotFound},
+		{"unknown type", "PATCH", "/api/cli/services/ftp/3/enable", node, "", http.StatusUnprocessableEntity},
+		{"unknown service", "PATCH", "/api/cli/services/tcp/999/enable", node, "", http.StatusNotFound},
+		{"non-numeric id", "PATCH", "/api/cli/services/tcp/db/enable", node, "", http.StatusUnprocessableEntity},
+		{"unknown route", "GET", "/api/cli/unknown", node, "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, body := call(tt.method, tt.path, tt.token, tt.body); got != tt.want {
+				t.Errorf("%s %s = %d %v, want %d", tt.method, tt.path, got, body, tt.want)
+			}
+		})
+	}
+}
+
+// Regenerating the keys must invalidate the old token.
+func TestRegenerateRotatesToken(t *testing.T) {
+	server := fake.NewServer(fake.Options{})
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("PATCH", srv.URL+"/api/cli/regenerate", nil)
+	req.Header.Set("Authorization", "Bearer "+fake.DefaultNodeToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var node struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&node); err != nil {
+		t.Fatal(err)
+	}
+
+	if node.Token == "" || node.Token == fake.DefaultNodeToken {
+		t.Fatalf("new token = %q, want a different one", node.Token)
+	}
+	if _, ok := server.Node(fake.DefaultNodeToken); ok {
+		t.Error("the old token still authenticates")
+	}
+	if _, ok := server.Node(node.Token); !ok {
+		t.Error("the new token does not authenticate")
+	}
+}
@@ -0,0 +1,318 @@
+package wiredoor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+)
+
+// ServiceHealth is the result of the latest backend probes of a service.
+type ServiceHealth struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Target    string    `json:"target"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"` // consecutive failed probes
+	LastError string    `json:"lastError,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+
+	// AutoDisabled is set when the daemon disabled the service after too
+	// many failures; it is enabled again once the backend recovers.
+	AutoDisabled bool `json:"autoDisabled,omitempty"`
+
+	// DisabledAt is the update time the server reported when the daemon
+	// disabled the service. A later update means someone else changed
+	// the service, so the daemon no longer re-enables it.
+	DisabledAt time.Time `json:"disabledAt,omitzero"`
+}
+
+// HealthState is written by the daemon after each probe round and read by
+// 'wiredoor status'. Each profile has its own state, as service IDs are
+// only unique on one server. Services are keyed by "<type>/<id>".
+type HealthState struct {
+	UpdatedAt time.Time                `json:"updatedAt"`
+	Services  map[string]ServiceHealth `json:"services"`
+}
+
+// lastHealthProbe keeps the daemon from probing more often than
+// [health] interval, whatever the watch interval is.
+var lastHealthProbe time.Time
+
+// GetHealthStateLocation returns the state file of the active profile:
+// health.json for the default profile and health-<profile>.json for the
+// others.
+func GetHealthStateLocation() string {
+	name := "health.json"
+	if p := ActiveProfile(); p != DefaultProfile {
+		name = "health-" + url.PathEscape(p) + ".json"
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("PROGRAMDATA"), "wiredoor", name)
+	default:
+		return filepath.Join("/var/run/wiredoor", name)
+	}
+}
+
+// LoadHealthState reads the state written by the daemon. A missing file
+// gives an empty state.
+func LoadHealthState() (HealthState, error) {
+	state := HealthState{Services: map[string]ServiceHealth{}}
+
+	data, err := os.ReadFile(GetHealthStateLocation())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid health state %s: %w", GetHealthStateLocation(), err)
+	}
+	if state.Services == nil {
+		state.Services = map[string]ServiceHealth{}
+	}
+
+	return state, nil
+}
+
+// SaveHealthState replaces the state file, so readers never see it half
+// written.
+func SaveHealthState(state HealthState) error {
+	location := GetHealthStateLocation()
+
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := location + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, location)
+}
+
+// Label is the text of the HEALTH column in 'wiredoor status'.
+func (h ServiceHealth) Label() string {
+	switch {
+	case h.AutoDisabled:
+		return "unhealthy (disabled)"
+	case !h.Healthy:
+		return "unhealthy"
+	case h.Failures > 0:
+		return fmt.Sprintf("failing (%d)", h.Failures)
+	default:
+		return "healthy"
+	}
+}
+
+func (s HealthState) label(serviceType string, id int64) string {
+	health, ok := s.Services[serviceType+"/"+strconv.FormatInt(id, 10)]
+	if !ok {
+		return "-"
+	}
+	return health.Label()
+}
+
+// probeServices checks the backends of the enabled services when [health]
+// is enabled and its interval has passed. After [health] failures
+// consecutive failures a service is reported unhealthy and, with
+// auto_disable, disabled until its backend answers again.
+func probeServices(ctx context.Context) {
+	config := GetHealthConfig()
+	if !parseBool(config.Enabled) {
+		return
+	}
+
+	interval := time.Duration(parseInt(config.Interval, 30)) * time.Second
+	if time.Since(lastHealthProbe) < interval {
+		return
+	}
+	lastHealthProbe = time.Now()
+
+	node, err := GetNode(ctx)
+	if err != nil {
+		slog.Warn("Unable to retrieve node information for health checks", "error", err)
+		return
+	}
+
+	previous, err := LoadHealthState()
+	if err != nil {
+		slog.Warn("Ignoring previous health state", "error", err)
+	}
+
+	prober := healthProber{
+		timeout:     time.Duration(parseInt(config.Timeout, 5)) * time.Second,
+		threshold:   max(parseInt(config.Failures, 3), 1),
+		autoDisable: parseBool(config.AutoDisable),
+	}
+
+	state := HealthState{UpdatedAt: time.Now(), Services: map[string]ServiceHealth{}}
+
+	for i := range node.HttpServices {
+		svc := Service{Type: "http", Http: &node.HttpServices[i]}
+		prober.probe(ctx, svc, node.IsGateway, previous, state)
+	}
+	for i := range node.TcpServices {
+		svc := Service{Type: "tcp", Tcp: &node.TcpServices[i]}
+		prober.probe(ctx, svc, node.IsGateway, previous, state)
+	}
+
+	if err := SaveHealthState(state); err != nil {
+		slog.Warn("Unable to save health state", "error", err)
+	}
+}
+
+type healthProber struct {
+	timeout     time.Duration
+	threshold   int
+	autoDisable bool
+}
+
+// probe checks one service and records the result in state. Services
+// disabled by the user are skipped, including the ones the daemon disabled
+// that were changed since.
+func (p healthProber) probe(ctx context.Context, svc Service, isGateway bool, previous HealthState, state HealthState) {
+	key := svc.Type + "/" + strconv.FormatInt(svc.ID(), 10)
+	health := previous.Services[key]
+
+	if health.AutoDisabled && (svc.Enabled() || !svc.UpdatedAt().Equal(health.DisabledAt)) {
+		health.AutoDisabled = false
+		health.DisabledAt = time.Time{}
+	}
+
+	if !svc.Enabled() && !health.AutoDisabled {
+		return
+	}
+
+	health.Type = svc.Type
+	health.ID = svc.ID()
+	health.Name = svc.Name()
+	health.CheckedAt = time.Now()
+
+	var err error
+	if svc.Type == "http" {
+		healthPath := GetHealthPath(svc.Name())
+		health.Target = buildHttpTarget(*svc.Http, isGateway) + healthPath
+		err = probeHttpBackend(ctx, *svc.Http, isGateway, healthPath, p.timeout)
+	} else {
+		health.Target = buildTcpTarget(*svc.Tcp, isGateway)
+		err = utils.ProbeBackend(backendHost(svc.Tcp.BackendHost, isGateway), svc.Tcp.BackendPort, svc.Tcp.Proto, p.timeout)
+	}
+
+	id := strconv.FormatInt(svc.ID(), 10)
+
+	if err == nil {
+		if !health.Healthy && health.Failures > 0 {
+			slog.Info("Service backend recovered", "service", health.Name, "target", health.Target)
+		}
+		health.Failures = 0
+		health.LastError = ""
+
+		if health.AutoDisabled {
+			ttl, err := RemainingTTL(svc.ExpiresAt())
+			if errors.Is(err, ErrExpired) {
+				slog.Info("Not re-enabling expired service", "service", health.Name)
+				health.AutoDisabled = false
+				health.DisabledAt = time.Time{}
+			} else if _, err := EnableServiceByType(ctx, EnableRequest{ServiceType: svc.Type, ID: id, Ttl: ttl}); err != nil {
+				slog.Warn("Unable to re-enable service", "service", health.Name, "error", err)
+			} else {
+				slog.Info("Service re-enabled after its backend recovered", "service", health.Name)
+				health.AutoDisabled = false
+				health.DisabledAt = time.Time{}
+			}
+		}
+	} else {
+		health.Failures++
+		health.LastError = err.Error()
+		slog.Warn("Service backend health check failed", "service", health.Name, "target", health.Target, "failures", health.Failures, "error", err)
+
+		if health.Failures >= p.threshold && p.autoDisable && svc.Enabled() {
+			if disabled, err := DisableServiceByType(ctx, svc.Type, id); err != nil {
+				slog.Warn("Unable to disable unhealthy service", "service", health.Name, "error", err)
+			} else {
+				slog.Warn("Service disabled after failed health checks", "service", health.Name, "failures", health.Failures)
+				health.AutoDisabled = true
+				health.DisabledAt = disabled.UpdatedAt()
+			}
+		}
+	}
+
+	health.Healthy = health.Failures < p.threshold
+	state.Services[key] = health
+}
+
+// probeHttpBackend sends a GET to healthPath when one is configured, and
+// otherwise only checks that the backend accepts connections. Any status
+// below 400 is healthy; certificates are not verified.
+func probeHttpBackend(ctx context.Context, svc HttpService, isGateway bool, healthPath string, timeout time.Duration) error {
+	host := backendHost(svc.BackendHost, isGateway)
+
+	if healthPath == "" {
+		return utils.ProbeBackend(host, svc.BackendPort, svc.BackendProto, timeout)
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	if !strings.HasPrefix(healthPath, "/") {
+		healthPath = "/" + healthPath
+	}
+
+	url := svc.BackendProto + "://" + net.JoinHostPort(host, strconv.Itoa(svc.BackendPort)) + healthPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	client.CloseIdleConnections()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %s", healthPath, resp.Status)
+	}
+	return nil
+}
+
+// backendHost is where the node reaches a backend: its backend host on
+// gateways, localhost otherwise.
+func backendHost(host string, isGateway bool) string {
+	if isGateway && host != "" {
+		return host
+	}
+	return "localhost"
+}
@@ -0,0 +1,73 @@
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Client talks to the inspector API of a running 'wiredoor http --inspect'.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient returns a client for the inspector UI listening on address
+// (host:port).
+func NewClient(address string) *Client {
+	return &Client{
+		base: "http://" + address,
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) List(ctx context.Context) ([]Exchange, error) {
+	var exchanges []Exchange
+	err := c.do(ctx, http.MethodGet, "/api/requests", &exchanges)
+	return exchanges, err
+}
+
+func (c *Client) Get(ctx context.Context, id int64) (Exchange, error) {
+	var ex Exchange
+	err := c.do(ctx, http.MethodGet, "/api/requests/"+strconv.FormatInt(id, 10), &ex)
+	return ex, err
+}
+
+func (c *Client) Replay(ctx context.Context, id int64) (Exchange, error) {
+	var ex Exchange
+	err := c.do(ctx, http.MethodPost, "/api/requests/"+strconv.FormatInt(id, 10)+"/replay", &ex)
+	return ex, err
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("inspector not reachable at %s (is 'wiredoor http --inspect' running?): %w", c.base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if body.Message == "" {
+			body.Message = resp.Status
+		}
+		return errors.New(body.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
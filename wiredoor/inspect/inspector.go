@@ -0,0 +1,307 @@
+// Package inspect implements the request inspector behind
+// 'wiredoor http --inspect': a reverse proxy that records the requests sent
+// to a backend, a local web UI and API to browse them, and replays.
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCapacity  = 100
+	DefaultBodyLimit = 64 << 10
+	DefaultUIAddress = "127.0.0.1:4040"
+)
+
+// ErrNotFound is returned for request IDs that are not (or no longer) in
+// the buffer.
+var ErrNotFound = errors.New("request not found")
+
+// Exchange is a recorded request and the backend's response. Bodies are
+// cut at the body limit, which the Truncated flags report.
+type Exchange struct {
+	ID       int64     `json:"id"`
+	ReplayOf int64     `json:"replayOf,omitempty"`
+	Time     time.Time `json:"time"`
+	Duration int64     `json:"durationMs"`
+
+	Method                string      `json:"method"`
+	URL                   string      `json:"url"`
+	Host                  string      `json:"host"`
+	RequestHeaders        http.Header `json:"requestHeaders"`
+	RequestBody           []byte      `json:"requestBody,omitempty"`
+	RequestBodyTruncated  bool        `json:"requestBodyTruncated,omitempty"`
+	Status                int         `json:"status,omitempty"`
+	ResponseHeaders       http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody          []byte      `json:"responseBody,omitempty"`
+	ResponseBodyTruncated bool        `json:"responseBodyTruncated,omitempty"`
+	Error                 string      `json:"error,omitempty"`
+}
+
+type Options struct {
+	Target    *url.URL // backend, e.g. http://localhost:3000
+	Capacity  int      // exchanges kept, default DefaultCapacity
+	BodyLimit int64    // bytes kept per body, default DefaultBodyLimit
+}
+
+// Inspector is an http.Handler that proxies to the target and keeps the
+// latest exchanges in a ring buffer.
+type Inspector struct {
+	target    *url.URL
+	capacity  int
+	bodyLimit int64
+
+	proxy     *httputil.ReverseProxy
+	transport http.RoundTripper
+
+	// replayToken is embedded in the UI's replay forms, so other web pages
+	// cannot make the browser replay requests.
+	replayToken string
+
+	mu        sync.Mutex
+	nextID    int64
+	exchanges []Exchange // oldest first
+}
+
+func New(opts Options) *Inspector {
+	i := &Inspector{
+		target:    opts.Target,
+		capacity:  opts.Capacity,
+		bodyLimit: opts.BodyLimit,
+	}
+	if i.capacity <= 0 {
+		i.capacity = DefaultCapacity
+	}
+	if i.bodyLimit <= 0 {
+		i.bodyLimit = DefaultBodyLimit
+	}
+
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	i.replayToken = hex.EncodeToString(token)
+
+	// Local backends often use self-signed certificates.
+	i.transport = &recordingTransport{
+		inspector: i,
+		base: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	i.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(i.target)
+			// Keep the public host name, as the gateway sends it.
+			r.Out.Host = r.In.Host
+		},
+		Transport:     i.transport,
+		FlushInterval: -1,
+	}
+
+	return i
+}
+
+func (i *Inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.proxy.ServeHTTP(w, r)
+}
+
+// Exchanges returns the recorded exchanges, newest first.
+func (i *Inspector) Exchanges() []Exchange {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	list := make([]Exchange, 0, len(i.exchanges))
+	for n := len(i.exchanges) - 1; n >= 0; n-- {
+		list = append(list, i.exchanges[n])
+	}
+	return list
+}
+
+func (i *Inspector) Exchange(id int64) (Exchange, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, ex := range i.exchanges {
+		if ex.ID == id {
+			return ex, nil
+		}
+	}
+	return Exchange{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+}
+
+// Replay sends a recorded request to the backend again and returns the new
+// exchange, which is recorded as well.
+func (i *Inspector) Replay(ctx context.Context, id int64) (Exchange, error) {
+	original, err := i.Exchange(id)
+	if err != nil {
+		return Exchange{}, err
+	}
+	if original.RequestBodyTruncated {
+		return Exchange{}, fmt.Errorf("request %d cannot be replayed: its body was larger than %d bytes", id, i.bodyLimit)
+	}
+
+	target, err := i.target.Parse(original.URL)
+	if err != nil {
+		return Exchange{}, err
+	}
+
+	ctx = context.WithValue(ctx, replayKey{}, id)
+	req, err := http.NewRequestWithContext(ctx, original.Method, target.String(), bytes.NewReader(original.RequestBody))
+	if err != nil {
+		return Exchange{}, err
+	}
+	req.Header = original.RequestHeaders.Clone()
+	req.Header.Del("Content-Length")
+	req.Host = original.Host
+
+	// A failed replay is recorded with its error like any other exchange.
+	resp, err := i.transport.RoundTrip(req)
+	if err == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	for _, ex := range i.Exchanges() {
+		if ex.ReplayOf == id {
+			return ex, nil
+		}
+	}
+	return Exchange{}, fmt.Errorf("%w: replay of %d", ErrNotFound, id)
+}
+
+func (i *Inspector) record(ex Exchange) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.nextID++
+	ex.ID = i.nextID
+
+	if len(i.exchanges) == i.capacity {
+		i.exchanges = append(i.exchanges[:0], i.exchanges[1:]...)
+	}
+	i.exchanges = append(i.exchanges, ex)
+}
+
+type replayKey struct{}
+
+// recordingTransport records every round trip made to the backend, both
+// proxied requests and replays. An exchange is recorded once the response
+// body is closed, or right away when the request fails.
+type recordingTransport struct {
+	inspector *Inspector
+	base      http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.inspector.bodyLimit
+
+	ex := Exchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            req.URL.RequestURI(),
+		Host:           req.Host,
+		RequestHeaders: req.Header.Clone(),
+	}
+	if id, ok := req.Context().Value(replayKey{}).(int64); ok {
+		ex.ReplayOf = id
+	}
+
+	requestBody := &limitedBuffer{limit: limit}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, requestBody), Closer: req.Body}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	ex.RequestBody, ex.RequestBodyTruncated = requestBody.Contents()
+	if err != nil {
+		ex.Duration = time.Since(ex.Time).Milliseconds()
+		ex.Error = err.Error()
+		t.inspector.record(ex)
+		return nil, err
+	}
+
+	ex.Status = resp.StatusCode
+	ex.ResponseHeaders = resp.Header.Clone()
+
+	responseBody := &limitedBuffer{limit: limit}
+	resp.Body = &recordingBody{
+		Reader: io.TeeReader(resp.Body, responseBody),
+		body:   resp.Body,
+		done: func() {
+			ex.Duration = time.Since(ex.Time).Milliseconds()
+			ex.ResponseBody, ex.ResponseBodyTruncated = responseBody.Contents()
+			// The request body is complete once the response is read.
+			ex.RequestBody, ex.RequestBodyTruncated = requestBody.Contents()
+			t.inspector.record(ex)
+		},
+	}
+
+	return resp, nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recordingBody calls done once, when the response body is closed.
+type recordingBody struct {
+	io.Reader
+	body io.Closer
+	once sync.Once
+	done func()
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// limitedBuffer keeps the first limit bytes written to it and accepts the
+// rest without storing it. The transport may still be writing the request
+// body when the response is recorded, hence the lock.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	room := b.limit - int64(b.buf.Len())
+	if int64(len(p)) > room {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// Contents returns a copy of the kept bytes and whether some were dropped.
+func (b *limitedBuffer) Contents() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf.Len() == 0 {
+		return nil, b.truncated
+	}
+	return bytes.Clone(b.buf.Bytes()), b.truncated
+}
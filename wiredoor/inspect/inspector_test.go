@@ -0,0 +1,216 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newInspector starts a backend echoing request bodies and an inspector
+// proxying to it, and returns the inspector and the proxy URL.
+func newInspector(t *testing.T, opts Options) (*Inspector, string, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("X-Host", r.Host)
+		_, _ = io.Copy(w, r.Body)
+	}))
+	t.Cleanup(backend.Close)
+
+	opts.Target, _ = url.Parse(backend.URL)
+	inspector := New(opts)
+
+	proxy := httptest.NewServer(inspector)
+	t.Cleanup(proxy.Close)
+
+	return inspector, proxy.URL, &calls
+}
+
+func send(t *testing.T, method, target, body string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "app.example.com"
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestCapacity(t *testing.T) {
+	inspector, proxy, _ := newInspector(t, Options{Capacity: 3})
+
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5"} {
+		send(t, "GET", proxy+path, "")
+	}
+
+	exchanges := inspector.Exchanges()
+	var got []string
+	for _, ex := range exchanges {
+		got = append(got, ex.URL)
+	}
+	if strings.Join(got, " ") != "/5 /4 /3" {
+		t.Errorf("kept %q, want the 3 newest, newest first", got)
+	}
+
+	if _, err := inspector.Exchange(exchanges[2].ID - 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("evicted exchange: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantBody      string
+		wantTruncated bool
+	}{
+		{name: "empty", body: "", wantBody: ""},
+		{name: "under the limit", body: "0123", wantBody: "0123"},
+		{name: "at the limit", body: "01234567", wantBody: "01234567"},
+		{name: "over the limit", body: "0123456789abcdef", wantBody: "01234567", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspector, proxy, _ := newInspector(t, Options{BodyLimit: 8})
+			send(t, "POST", proxy+"/upload", tt.body)
+
+			ex := inspector.Exchanges()[0]
+			if string(ex.RequestBody) != tt.wantBody || ex.RequestBodyTruncated != tt.wantTruncated {
+				t.Errorf("request body = %q (truncated %v), want %q (truncated %v)", ex.RequestBody, ex.RequestBodyTruncated, tt.wantBody, tt.wantTruncated)
+			}
+			// The backend echoes the request body.
+			if string(ex.ResponseBody) != tt.wantBody || ex.ResponseBodyTruncated != tt.wantTruncated {
+				t.Errorf("response body = %q (truncated %v), want %q (truncated %v)", ex.ResponseBody, ex.ResponseBodyTruncated, tt.wantBody, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	inspector, proxy, calls := newInspector(t, Options{BodyLimit: 8})
+	send(t, "POST", proxy+"/hook?x=1", "payload")
+	send(t, "POST", proxy+"/big", "0123456789")
+
+	original := inspector.Exchanges()[1]
+	replay, err := inspector.Replay(context.Background(), original.ID)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	if replay.ReplayOf != original.ID || replay.ID == original.ID {
+		t.Errorf("replay id %d of %d, want a new exchange replaying %d", replay.ID, replay.ReplayOf, original.ID)
+	}
+	if replay.Method != "POST" || replay.URL != "/hook?x=1" || string(replay.RequestBody) != "payload" {
+		t.Errorf("replayed %s %s %q, want POST /hook?x=1 \"payload\"", replay.Method, replay.URL, replay.RequestBody)
+	}
+	if host := replay.ResponseHeaders.Get("X-Host"); host != "app.example.com" {
+		t.Errorf("backend saw host %q, want the original app.example.com", host)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("backend called %d times, want 3", n)
+	}
+
+	truncated := inspector.Exchanges()[1]
+	if _, err := inspector.Replay(context.Background(), truncated.ID); err == nil || !strings.Contains(err.Error(), "cannot be replayed") {
+		t.Errorf("replay of a truncated body: error = %v, want it refused", err)
+	}
+	if _, err := inspector.Replay(context.Background(), 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("replay of an unknown id: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHandlerReplay(t *testing.T) {
+	inspector, proxy, calls := newInspector(t, Options{})
+	send(t, "POST", proxy+"/hook", "payload")
+
+	ui := httptest.NewServer(inspector.Handler())
+	defer ui.Close()
+
+	client := NewClient(strings.TrimPrefix(ui.URL, "http://"))
+	ex, err := client.Replay(context.Background(), 1)
+	if err != nil || ex.ReplayOf != 1 {
+		t.Fatalf("client Replay = %+v, %v; want a replay of 1", ex, err)
+	}
+
+	tests := []struct {
+		name        string
+		host        string
+		path        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "api list", path: "/api/requests", want: http.StatusOK},
+		{name: "api list by localhost", host: "localhost:4040", path: "/api/requests", want: http.StatusOK},
+		{name: "foreign host", host: "attacker.example.com", path: "/api/requests", want: http.StatusForbidden},
+		{name: "foreign host on the ui", host: "attacker.example.com:4040", path: "/", want: http.StatusForbidden},
+		{name: "api replay as a form", path: "/api/requests/1/replay", contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{name: "api replay as text", path: "/api/requests/1/replay", contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{name: "api replay", path: "/api/requests/1/replay", contentType: "application/json; charset=utf-8", want: http.StatusOK},
+		{name: "api replay of unknown id", path: "/api/requests/999/replay", contentType: "application/json", want: http.StatusNotFound},
+		{name: "form replay without token", path: "/requests/1/replay", contentType: "application/x-www-form-urlencoded", want: http.StatusForbidden},
+		{name: "form replay with wrong token", path: "/requests/1/replay", contentType: "application/x-www-form-urlencoded", body: "token=guess", want: http.StatusForbidden},
+		{name: "form replay", path: "/requests/1/replay", contentType: "application/x-www-form-urlencoded", body: "token=" + inspector.replayToken, want: http.StatusSeeOther},
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := "GET"
+			if strings.HasSuffix(tt.path, "/replay") {
+				method = "POST"
+			}
+			req, err := http.NewRequest(method, ui.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			resp, err := noRedirect.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	// The client replay, the API replay and the form replay reached the
+	// backend; the refused ones did not.
+	if n := calls.Load(); n != 4 {
+		t.Errorf("backend called %d times, want 4", n)
+	}
+
+	resp, err := http.Get(ui.URL + "/requests/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), `name="token" value="`+inspector.replayToken+`"`) {
+		t.Error("the detail page does not carry the replay token")
+	}
+}
@@ -0,0 +1,194 @@
+package inspect
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Handler serves the inspector web UI and the JSON API used by
+// 'wiredoor inspect' and 'wiredoor replay':
+//
+//	GET  /api/requests              recorded exchanges, newest first
+//	GET  /api/requests/{id}         one exchange
+//	POST /api/requests/{id}/replay  replay an exchange
+//
+// The captured requests hold credentials, so only requests addressed to
+// localhost or an IP address are served, which defeats DNS rebinding.
+// Replays need the token of the UI's forms, or a JSON content type in the
+// API, which other web pages cannot send without a CORS preflight.
+func (i *Inspector) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", i.serveIndex)
+	mux.HandleFunc("GET /requests/{id}", i.serveDetail)
+	mux.HandleFunc("POST /requests/{id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.PostFormValue("token")), []byte(i.replayToken)) != 1 {
+			http.Error(w, "invalid replay token", http.StatusForbidden)
+			return
+		}
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		ex, err := i.Replay(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		http.Redirect(w, r, "/requests/"+strconv.FormatInt(ex.ID, 10), http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("GET /api/requests", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, i.Exchanges())
+	})
+	mux.HandleFunc("GET /api/requests/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		ex, err := i.Exchange(id)
+		if err != nil {
+			writeJSON(w, errorStatus(err), map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, ex)
+	})
+	mux.HandleFunc("POST /api/requests/{id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"message": "replay requires Content-Type: application/json"})
+			return
+		}
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		ex, err := i.Replay(r.Context(), id)
+		if err != nil {
+			writeJSON(w, errorStatus(err), map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, ex)
+	})
+
+	return localOnly(mux)
+}
+
+// localOnly rejects requests whose Host is a domain name other than
+// localhost.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		host = strings.Trim(host, "[]")
+
+		if host != "localhost" && !strings.HasSuffix(host, ".localhost") && net.ParseIP(host) == nil {
+			http.Error(w, "invalid Host header", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (i *Inspector) serveIndex(w http.ResponseWriter, r *http.Request) {
+	render(w, indexTemplate, map[string]any{
+		"Target":    i.target.String(),
+		"Exchanges": i.Exchanges(),
+	})
+}
+
+func (i *Inspector) serveDetail(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	ex, err := i.Exchange(id)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	render(w, detailTemplate, struct {
+		Exchange
+		ReplayToken string
+	}{ex, i.replayToken})
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// BodyText returns body for display, or a placeholder for binary content.
+func BodyText(body []byte) string {
+	if utf8.Valid(body) {
+		return string(body)
+	}
+	return "(" + strconv.Itoa(len(body)) + " bytes of binary data)"
+}
+
+const pageStyle = `<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; font-family: monospace; }
+pre { background: #f5f5f5; padding: 1em; overflow-x: auto; white-space: pre-wrap; }
+.error { color: #b00; }
+</style>`
+
+var funcs = template.FuncMap{"body": BodyText}
+
+var indexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><title>Wiredoor inspector</title><meta http-equiv="refresh" content="3">` + pageStyle + `</head>
+<body>
+<h1>Requests to {{.Target}}</h1>
+{{if .Exchanges}}
+<table>
+<tr><th>ID</th><th>TIME</th><th>METHOD</th><th>URL</th><th>STATUS</th><th>DURATION</th></tr>
+{{range .Exchanges}}
+<tr>
+<td><a href="/requests/{{.ID}}">{{.ID}}</a>{{if .ReplayOf}} (replay of {{.ReplayOf}}){{end}}</td>
+<td>{{.Time.Format "15:04:05"}}</td>
+<td>{{.Method}}</td>
+<td>{{.URL}}</td>
+<td>{{if .Error}}<span class="error">error</span>{{else}}{{.Status}}{{end}}</td>
+<td>{{.Duration}} ms</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No requests yet. This page refreshes automatically.</p>
+{{end}}
+</body></html>`))
+
+var detailTemplate = template.Must(template.New("detail").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><title>Request {{.ID}}</title>` + pageStyle + `</head>
+<body>
+<p><a href="/">&larr; All requests</a></p>
+<h1>{{.Method}} {{.URL}}</h1>
+<form method="post" action="/requests/{{.ID}}/replay"><input type="hidden" name="token" value="{{.ReplayToken}}"><button type="submit">Replay</button></form>
+<p>{{.Time.Format "2006-01-02 15:04:05"}} &middot; {{.Duration}} ms &middot; Host: {{.Host}}{{if .ReplayOf}} &middot; replay of <a href="/requests/{{.ReplayOf}}">{{.ReplayOf}}</a>{{end}}</p>
+<h2>Request</h2>
+<pre>{{range $k, $v := .RequestHeaders}}{{$k}}: {{range $v}}{{.}} {{end}}
+{{end}}</pre>
+{{if .RequestBody}}<pre>{{body .RequestBody}}</pre>{{end}}
+{{if .RequestBodyTruncated}}<p>Body truncated.</p>{{end}}
+<h2>Response</h2>
+{{if .Error}}<p class="error">{{.Error}}</p>{{else}}
+<p>Status {{.Status}}</p>
+<pre>{{range $k, $v := .ResponseHeaders}}{{$k}}: {{range $v}}{{.}} {{end}}
+{{end}}</pre>
+{{if .ResponseBody}}<pre>{{body .ResponseBody}}</pre>{{end}}
+{{if .ResponseBodyTruncated}}<p>Body truncated.</p>{{end}}
+{{end}}
+</body></html>`))
@@ -0,0 +1,366 @@
+package wiredoor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+)
+
+// ServiceColumns lists the columns known to PrintServiceList, in display
+// order. DefaultServiceColumns is used when no selection is given.
+var (
+	ServiceColumns        = []string{"ID", "TYPE", "STATE", "NAME", "DOMAIN", "PROTO", "PUBLIC", "TARGET", "TTL", "EXPIRES"}
+	DefaultServiceColumns = []string{"ID", "TYPE", "STATE", "NAME", "PUBLIC", "TARGET", "EXPIRES"}
+)
+
+type ServiceFilter struct {
+	Name   string // glob matched against the service name
+	Domain string // glob matched against the service domain
+	State  string // "enabled", "disabled" or empty for both
+	Proto  string // backend proto for HTTP (http, https), tcp or udp for TCP
+}
+
+// ListServices fetches the HTTP and/or TCP services of the node. An empty
+// serviceType returns both, HTTP first.
+func ListServices(ctx context.Context, serviceType string) ([]Service, error) {
+	return DefaultClient().ListServices(ctx, serviceType)
+}
+
+func (c *Client) ListServices(ctx context.Context, serviceType string) ([]Service, error) {
+	var services []Service
+
+	if serviceType == "" || serviceType == "http" {
+		httpServices, err := c.GetServices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range httpServices {
+			services = append(services, Service{Type: "http", Http: &httpServices[i]})
+		}
+	}
+
+	if serviceType == "" || serviceType == "tcp" {
+		tcpServices, err := c.GetTcpServices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tcpServices {
+			services = append(services, Service{Type: "tcp", Tcp: &tcpServices[i]})
+		}
+	}
+
+	return services, nil
+}
+
+// ResolveService finds a service by name or, when no name matches, by
+// numeric ID. An empty serviceType searches both HTTP and TCP services.
+func ResolveService(ctx context.Context, serviceType string, ref string) (Service, error) {
+	return DefaultClient().ResolveService(ctx, serviceType, ref)
+}
+
+func (c *Client) ResolveService(ctx context.Context, serviceType string, ref string) (Service, error) {
+	services, err := c.ListServices(ctx, serviceType)
+	if err != nil {
+		return Service{}, err
+	}
+
+	matches := slices.DeleteFunc(slices.Clone(services), func(s Service) bool { return s.Name() != ref })
+	if len(matches) == 0 {
+		matches = slices.DeleteFunc(services, func(s Service) bool { return strconv.FormatInt(s.ID(), 10) != ref })
+	}
+
+	switch len(matches) {
+	case 0:
+		kind := "service"
+		if serviceType != "" {
+			kind = strings.ToUpper(serviceType) + " service"
+		}
+		return Service{}, fmt.Errorf("%w: no %s matches %q", ErrServiceNotFound, kind, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return Service{}, &AmbiguousServiceError{Ref: ref, Candidates: matches}
+	}
+}
+
+func (s Service) ID() int64 {
+	if s.Http != nil {
+		return s.Http.ID
+	}
+	if s.Tcp != nil {
+		return s.Tcp.ID
+	}
+	return 0
+}
+
+func (s Service) Name() string {
+	if s.Http != nil {
+		return s.Http.Name
+	}
+	if s.Tcp != nil {
+		return s.Tcp.Name
+	}
+	return ""
+}
+
+func (s Service) Domain() string {
+	if s.Http != nil {
+		return s.Http.Domain
+	}
+	if s.Tcp != nil {
+		return s.Tcp.Domain
+	}
+	return ""
+}
+
+// Proto is the backend protocol for HTTP services and the transport
+// protocol for TCP services.
+func (s Service) Proto() string {
+	if s.Http != nil {
+		return s.Http.BackendProto
+	}
+	if s.Tcp != nil {
+		return s.Tcp.Proto
+	}
+	return ""
+}
+
+func (s Service) Enabled() bool {
+	if s.Http != nil {
+		return s.Http.Enabled
+	}
+	if s.Tcp != nil {
+		return s.Tcp.Enabled
+	}
+	return false
+}
+
+func (s Service) ExpiresAt() *time.Time {
+	if s.Http != nil {
+		return s.Http.ExpiresAt
+	}
+	if s.Tcp != nil {
+		return s.Tcp.ExpiresAt
+	}
+	return nil
+}
+
+func (s Service) UpdatedAt() time.Time {
+	if s.Http != nil {
+		return s.Http.UpdatedAt
+	}
+	if s.Tcp != nil {
+		return s.Tcp.UpdatedAt
+	}
+	return time.Time{}
+}
+
+func (s Service) column(name string) string {
+	switch name {
+	case "ID":
+		return strconv.FormatInt(s.ID(), 10)
+	case "TYPE":
+		return s.Type
+	case "STATE":
+		if s.Enabled() {
+			return "enabled"
+		}
+		return "disabled"
+	case "NAME":
+		return s.Name()
+	case "DOMAIN":
+		return dash(s.Domain())
+	case "PROTO":
+		if s.Tcp != nil && s.Tcp.Ssl {
+			return s.Proto() + "/ssl"
+		}
+		return s.Proto()
+	case "PUBLIC":
+		if s.Http != nil {
+			return s.Http.PublicAccess
+		}
+		if s.Tcp != nil {
+			return s.Tcp.PublicAccess
+		}
+	case "TARGET":
+		if s.Http != nil {
+			return buildHttpTarget(*s.Http, s.Http.BackendHost != "")
+		}
+		if s.Tcp != nil {
+			return buildTcpTarget(*s.Tcp, s.Tcp.BackendHost != "")
+		}
+	case "TTL":
+		if s.Http != nil {
+			return dash(s.Http.Ttl)
+		}
+		if s.Tcp != nil {
+			return dash(s.Tcp.Ttl)
+		}
+	case "EXPIRES":
+		return formatExpiry(s.ExpiresAt())
+	}
+	return "-"
+}
+
+// FilterServices returns the services matching every non-empty field of
+// filter.
+func FilterServices(services []Service, filter ServiceFilter) ([]Service, error) {
+	for _, pattern := range []string{filter.Name, filter.Domain} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	state := strings.ToLower(filter.State)
+	if state != "" && state != "enabled" && state != "disabled" {
+		return nil, fmt.Errorf("invalid state %q: must be enabled or disabled", filter.State)
+	}
+
+	result := make([]Service, 0, len(services))
+	for _, svc := range services {
+		if filter.Name != "" && !globMatch(filter.Name, svc.Name()) {
+			continue
+		}
+		if filter.Domain != "" && !globMatch(filter.Domain, svc.Domain()) {
+			continue
+		}
+		if state != "" && svc.Enabled() != (state == "enabled") {
+			continue
+		}
+		if filter.Proto != "" && !strings.EqualFold(filter.Proto, svc.Proto()) {
+			continue
+		}
+		result = append(result, svc)
+	}
+
+	return result, nil
+}
+
+// SortServices sorts services in place by a column name, descending when
+// prefixed with "-". Services without an expiry sort last by EXPIRES.
+func SortServices(services []Service, key string) error {
+	desc := strings.HasPrefix(key, "-")
+	column := strings.ToUpper(strings.TrimPrefix(key, "-"))
+
+	var order func(a, b Service) int
+	switch column {
+	case "ID":
+		order = func(a, b Service) int { return cmp.Compare(a.ID(), b.ID()) }
+	case "EXPIRES":
+		order = func(a, b Service) int {
+			ea, eb := a.ExpiresAt(), b.ExpiresAt()
+			switch {
+			case ea == nil && eb == nil:
+				return 0
+			case ea == nil:
+				return 1
+			case eb == nil:
+				return -1
+			}
+			return ea.Compare(*eb)
+		}
+	default:
+		if !slices.Contains(ServiceColumns, column) {
+			return fmt.Errorf("invalid sort key %q: must be one of %s", key, strings.ToLower(strings.Join(ServiceColumns, ", ")))
+		}
+		order = func(a, b Service) int { return strings.Compare(a.column(column), b.column(column)) }
+	}
+
+	slices.SortStableFunc(services, func(a, b Service) int {
+		if desc {
+			return order(b, a)
+		}
+		return order(a, b)
+	})
+
+	return nil
+}
+
+// ParseServiceColumns turns a comma separated column list into column
+// names, rejecting unknown ones. An empty list selects the defaults.
+func ParseServiceColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultServiceColumns, nil
+	}
+
+	var columns []string
+	for _, name := range strings.Split(list, ",") {
+		column := strings.ToUpper(strings.TrimSpace(name))
+		if !slices.Contains(ServiceColumns, column) {
+			return nil, fmt.Errorf("invalid column %q: must be one of %s", name, strings.ToLower(strings.Join(ServiceColumns, ", ")))
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func PrintServiceList(services []Service, columns []string) {
+	if len(services) == 0 {
+		utils.Terminal().Println("No services found")
+		return
+	}
+
+	rows := make([][]string, 0, len(services))
+	for _, svc := range services {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, svc.column(column))
+		}
+		rows = append(rows, row)
+	}
+
+	utils.Terminal().Table(columns, rows)
+}
+
+// formatExpiry renders a TTL expiry as an absolute local time followed by
+// the time left.
+func formatExpiry(expiresAt *time.Time) string {
+	remaining := formatRemaining(expiresAt)
+	if expiresAt == nil || remaining == "expired" {
+		return remaining
+	}
+
+	return fmt.Sprintf("%s (in %s)", expiresAt.Local().Format("2006-01-02 15:04"), remaining)
+}
+
+// formatRemaining renders the time left before expiresAt, e.g. "1h30m".
+func formatRemaining(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return "-"
+	}
+
+	left := time.Until(*expiresAt)
+	if left <= 0 {
+		return "expired"
+	}
+
+	if left < time.Minute {
+		return "<1m"
+	}
+
+	remaining := strings.TrimSuffix(left.Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(remaining, "h0m") {
+		remaining = strings.TrimSuffix(remaining, "0m")
+	}
+	return remaining
+}
+
+func globMatch(pattern, value string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return ok
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package wiredoor_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/wiredoor/wiredoor-cli/wiredoor"
+)
+
+// seedListServices exposes the HTTP services web (enabled) and admin
+// (disabled, https) and the TCP services web and dns (udp), and returns
+// them by "<type>/<name>".
+func seedListServices(t *testing.T, client *wiredoor.Client) map[string]wiredoor.Service {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, params := range []wiredoor.HttpServiceParams{
+		{Name: "web", Domain: "web.example.com", PathLocation: "/", BackendProto: "http", BackendPort: 3000},
+		{Name: "admin", Domain: "admin.internal.example.com", PathLocation: "/", BackendProto: "https", BackendPort: 8443},
+	} {
+		if _, err := client.ExposeHTTP(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, params := range []wiredoor.TcpServiceParams{
+		{Name: "web", Proto: "tcp", BackendPort: 3001},
+		{Name: "dns", Proto: "udp", BackendPort: 53},
+	} {
+		if _, err := client.ExposeTCP(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	services, err := client.ListServices(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := map[string]wiredoor.Service{}
+	for _, svc := range services {
+		byName[svc.Type+"/"+svc.Name()] = svc
+	}
+	if _, err := client.DisableService(ctx, "http", strconv.FormatInt(byName["http/admin"].ID(), 10)); err != nil {
+		t.Fatal(err)
+	}
+	return byName
+}
+
+func TestResolveService(t *testing.T) {
+	client := newFakeClient(t)
+	services := seedListServices(t, client)
+
+	tests := []struct {
+		name        string
+		serviceType string
+		ref         string
+		want        string // "<type>/<name>" of the service found
+		wantErr     error
+		ambiguous   bool
+	}{
+		{name: "by name", ref: "dns", want: "tcp/dns"},
+		{name: "by name and type", serviceType: "tcp", ref: "web", want: "tcp/web"},
+		{name: "by id", ref: strconv.FormatInt(services["http/admin"].ID(), 10), want: "http/admin"},
+		{name: "id of another type", serviceType: "tcp", ref: strconv.FormatInt(services["http/admin"].ID(), 10), wantErr: wiredoor.ErrServiceNotFound},
+		{name: "unknown", ref: "missing", wantErr: wiredoor.ErrServiceNotFound},
+		{name: "same name in both types", ref: "web", ambiguous: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := client.ResolveService(context.Background(), tt.serviceType, tt.ref)
+
+			var ambiguous *wiredoor.AmbiguousServiceError
+			switch {
+			case tt.ambiguous:
+				if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+					t.Fatalf("error = %v, want an ambiguous match of 2 services", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case svc.Type+"/"+svc.Name() != tt.want:
+				t.Errorf("found %s/%s, want %s", svc.Type, svc.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterServices(t *testing.T) {
+	client := newFakeClient(t)
+	seedListServices(t, client)
+
+	tests := []struct {
+		name        string
+		serviceType string
+		filter      wiredoor.ServiceFilter
+		want        []string
+		wantErr     bool
+	}{
+		{name: "all", want: []string{"http/web", "http/admin", "tcp/web", "tcp/dns"}},
+		{name: "type", serviceType: "tcp", want: []string{"tcp/web", "tcp/dns"}},
+		{name: "name glob", filter: wiredoor.ServiceFilter{Name: "w*"}, want: []string{"http/web", "tcp/web"}},
+		{name: "domain glob", filter: wiredoor.ServiceFilter{Domain: "*.internal.*"}, want: []string{"http/admin"}},
+		{name: "enabled", filter: wiredoor.ServiceFilter{State: "enabled"}, want: []string{"http/web", "tcp/web", "tcp/dns"}},
+		{name: "disabled", filter: wiredoor.ServiceFilter{State: "Disabled"}, want: []string{"http/admin"}},
+		{name: "http proto", filter: wiredoor.ServiceFilter{Proto: "https"}, want: []string{"http/admin"}},
+		{name: "tcp proto", filter: wiredoor.ServiceFilter{Proto: "UDP"}, want: []string{"tcp/dns"}},
+		{name: "combined", serviceType: "http", filter: wiredoor.ServiceFilter{Name: "web", State: "enabled", Proto: "http"}, want: []string{"http/web"}},
+		{name: "no match", filter: wiredoor.ServiceFilter{Name: "nothing"}, want: nil},
+		{name: "invalid state", filter: wiredoor.ServiceFilter{State: "paused"}, wantErr: true},
+		{name: "invalid pattern", filter: wiredoor.ServiceFilter{Name: "["}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, err := client.ListServices(context.Background(), tt.serviceType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filtered, err := wiredoor.FilterServices(services, tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, svc := range filtered {
+				got = append(got, svc.Type+"/"+svc.Name())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
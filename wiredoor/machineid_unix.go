@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package wiredoor
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+)
+
+var platformUUIDPattern = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+// machineID returns a stable identifier of this host: /etc/machine-id, or
+// the hardware UUID on macOS.
+func machineID() (string, error) {
+	if runtime.GOOS == "darwin" {
+		out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err == nil {
+			if m := platformUUIDPattern.FindSubmatch(out); m != nil {
+				return string(m[1]), nil
+			}
+		}
+	}
+	return readMachineIDFile()
+}
@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package wiredoor
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+// machineID returns the MachineGuid set by Windows at installation.
+func machineID() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	id, _, err := key.GetStringValue("MachineGuid")
+	return id, err
+}
@@ -0,0 +1,295 @@
+package wiredoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the services a node should expose. It is read from
+// YAML or JSON; in both cases keys are the API field names, e.g.
+//
+//	http:
+//	  - name: web
+//	    domain: app.example.com
+//	    backendPort: 3000
+//	tcp:
+//	  - name: db
+//	    backendPort: 5432
+//	    enabled: false
+//
+// GatewayNetworks only applies to gateway nodes.
+type Manifest struct {
+	GatewayNetworks []GatewayNetwork    `json:"gatewayNetworks,omitempty"`
+	Http            []HttpManifestEntry `json:"http,omitempty"`
+	Tcp             []TcpManifestEntry  `json:"tcp,omitempty"`
+}
+
+type HttpManifestEntry struct {
+	HttpServiceParams
+	Enabled *bool `json:"enabled,omitempty"` // default true
+}
+
+type TcpManifestEntry struct {
+	TcpServiceParams
+	Enabled *bool `json:"enabled,omitempty"` // default true
+}
+
+func (e HttpManifestEntry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+func (e TcpManifestEntry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// LoadManifest reads a manifest from path, or from stdin when path is "-".
+// The format is detected from the content, so both YAML and JSON files are
+// accepted whatever their extension.
+func LoadManifest(path string) (Manifest, error) {
+	var data []byte
+	var err error
+
+	name := filepath.Base(path)
+	if path == "-" {
+		name = "stdin"
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest %s: %w", name, err)
+	}
+
+	return manifest, nil
+}
+
+// ParseManifest decodes a YAML or JSON manifest and validates it. Unknown
+// fields are rejected so typos do not silently drop settings.
+func ParseManifest(data []byte) (Manifest, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Manifest{}, err
+	}
+
+	// YAML is converted to JSON so the json struct tags apply to both.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return Manifest{}, err
+	}
+
+	return manifest, manifest.Validate()
+}
+
+// ExportOptions selects what ManifestFromNode includes besides the
+// services.
+type ExportOptions struct {
+	Gateway bool // the gateway networks of gateway nodes
+	Ports   bool // the public ports the server assigned to TCP services
+}
+
+// ManifestFromNode captures the services of node as a manifest that
+// recreates them. TCP ports are left out unless opts.Ports is set: the
+// server assigns them, and on another server they may be taken.
+func ManifestFromNode(node NodeInfo, opts ExportOptions) Manifest {
+	manifest := Manifest{}
+
+	if opts.Gateway && node.IsGateway {
+		manifest.GatewayNetworks = node.GatewayNetworks
+	}
+
+	for _, svc := range node.HttpServices {
+		enabled := svc.Enabled
+		manifest.Http = append(manifest.Http, HttpManifestEntry{HttpServiceParams: svc.HttpServiceParams, Enabled: &enabled})
+	}
+
+	for _, svc := range node.TcpServices {
+		enabled := svc.Enabled
+		params := svc.TcpServiceParams
+		if !opts.Ports {
+			params.Port = 0
+		}
+		manifest.Tcp = append(manifest.Tcp, TcpManifestEntry{TcpServiceParams: params, Enabled: &enabled})
+	}
+
+	return manifest
+}
+
+// Encode writes the manifest as "yaml" or "json". Empty fields are left
+// out so the result stays readable.
+func (m Manifest) Encode(w io.Writer, format string) error {
+	node, err := utils.YAMLNode(m)
+	if err != nil {
+		return err
+	}
+	dropEmpty(node)
+
+	switch format {
+	case "yaml", "":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		buf := &bytes.Buffer{}
+		writeNodeJSON(buf, node, "")
+		buf.WriteByte('\n')
+		_, err := w.Write(buf.Bytes())
+		return err
+	default:
+		return fmt.Errorf("invalid format %q: must be yaml or json", format)
+	}
+}
+
+// dropEmpty removes null, empty string and empty collection values from
+// the mappings of a YAML document.
+func dropEmpty(node *yaml.Node) {
+	for _, child := range node.Content {
+		dropEmpty(child)
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		empty := value.Tag == "!!null" ||
+			(value.Tag == "!!str" && value.Value == "") ||
+			((value.Kind == yaml.SequenceNode || value.Kind == yaml.MappingNode) && len(value.Content) == 0)
+		if !empty {
+			content = append(content, node.Content[i], value)
+		}
+	}
+	node.Content = content
+}
+
+// writeNodeJSON renders a YAML document decoded from JSON back to indented
+// JSON, keeping the key order.
+func writeNodeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		writeNodeJSON(buf, node.Content[0], indent)
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, end := "{", "}"
+		step := 2
+		if node.Kind == yaml.SequenceNode {
+			open, end, step = "[", "]", 1
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(open + end)
+			return
+		}
+		buf.WriteString(open + "\n")
+		inner := indent + "  "
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString(inner)
+			if step == 2 {
+				key, _ := json.Marshal(node.Content[i].Value)
+				buf.Write(key)
+				buf.WriteString(": ")
+			}
+			writeNodeJSON(buf, node.Content[i+step-1], inner)
+			if i+step < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + end)
+	default:
+		if node.Tag == "!!str" {
+			value, _ := json.Marshal(node.Value)
+			buf.Write(value)
+		} else {
+			buf.WriteString(node.Value)
+		}
+	}
+}
+
+// Validate checks what can be checked locally; the server validates the
+// rest when the manifest is applied.
+func (m Manifest) Validate() error {
+	seen := map[string]bool{}
+
+	for i, network := range m.GatewayNetworks {
+		if _, _, err := net.ParseCIDR(network.Subnet); err != nil {
+			return fmt.Errorf("gatewayNetworks[%d]: invalid subnet %q", i, network.Subnet)
+		}
+	}
+
+	for i, svc := range m.Http {
+		if svc.Name == "" {
+			return fmt.Errorf("http[%d]: name is required", i)
+		}
+		if seen["http/"+svc.Name] {
+			return fmt.Errorf("http[%d]: duplicate service name %q", i, svc.Name)
+		}
+		seen["http/"+svc.Name] = true
+		if _, err := ParseTTL(svc.Ttl); err != nil {
+			return fmt.Errorf("http[%d]: %w", i, err)
+		}
+	}
+
+	for i, svc := range m.Tcp {
+		if svc.Name == "" {
+			return fmt.Errorf("tcp[%d]: name is required", i)
+		}
+		if seen["tcp/"+svc.Name] {
+			return fmt.Errorf("tcp[%d]: duplicate service name %q", i, svc.Name)
+		}
+		seen["tcp/"+svc.Name] = true
+		if _, err := ParseTTL(svc.Ttl); err != nil {
+			return fmt.Errorf("tcp[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// normalize fills in the defaults the server applies, so a manifest and a
+// live service compare equal when they describe the same thing.
+func (e HttpManifestEntry) normalize() HttpServiceParams {
+	params := e.HttpServiceParams
+	if params.PathLocation == "" {
+		params.PathLocation = "/"
+	}
+	if params.BackendProto == "" {
+		params.BackendProto = "http"
+	}
+	params.BackendProto = strings.ToLower(params.BackendProto)
+	params.Ttl = comparableTtl(params.Ttl)
+	return params
+}
+
+func (e TcpManifestEntry) normalize() TcpServiceParams {
+	params := e.TcpServiceParams
+	if params.Proto == "" {
+		params.Proto = "tcp"
+	}
+	params.Proto = strings.ToLower(params.Proto)
+	params.Ttl = comparableTtl(params.Ttl)
+	return params
+}
@@ -0,0 +1,214 @@
+package wiredoor
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"gopkg.in/ini.v1"
+)
+
+// ConfigVersion is the config schema version written to [meta] version.
+// Files without it are version 0.
+const ConfigVersion = 2
+
+// ConfigChange is a change made by a config migration.
+type ConfigChange struct {
+	Version int    `json:"version"`
+	Change  string `json:"change"`
+}
+
+// configMigration upgrades a config file from version-1 to version and
+// describes what it changed. Migrations run in order and must leave files
+// that are already up to date untouched.
+type configMigration struct {
+	version int
+	apply   func(cfg *ini.File) []string
+}
+
+var configMigrations = []configMigration{
+	{version: 1, apply: addDefaultSettings},
+	{version: 2, apply: normalizeBooleans},
+}
+
+var migrateWarningOnce sync.Once
+
+// autoMigrate makes getIniFile rewrite outdated config files.
+var autoMigrate = true
+
+// migrationTried is set once getIniFile tried to rewrite the config file,
+// so the lock and backup are not attempted again on every read when the
+// file cannot be written.
+var migrationTried atomic.Bool
+
+// SetAutoMigrate turns off the migration of outdated config files on load,
+// for commands that inspect the file as it is. Migrations are then only
+// applied in memory.
+func SetAutoMigrate(enabled bool) {
+	autoMigrate = enabled
+}
+
+// addDefaultSettings writes the default of every key missing from the
+// file, so new settings are visible and documented on old installs.
+func addDefaultSettings(cfg *ini.File) []string {
+	var changes []string
+
+	for _, section := range configSections {
+		for _, key := range sortedDefaults(section) {
+			if cfg.Section(section).HasKey(key) {
+				continue
+			}
+			cfg.Section(section).Key(key).SetValue(defaultConfig[section][key])
+			changes = append(changes, fmt.Sprintf("add [%s] %s = %s", section, key, defaultConfig[section][key]))
+		}
+	}
+
+	return changes
+}
+
+// normalizeBooleans rewrites the yes/no, on/off and 1/0 spellings of
+// boolean settings as true or false.
+func normalizeBooleans(cfg *ini.File) []string {
+	var changes []string
+
+	for _, section := range cfg.Sections() {
+		keys := configKeys[section.Name()]
+		if strings.HasPrefix(section.Name(), profileSectionPrefix) {
+			keys = configKeys["server"]
+		}
+
+		for _, key := range section.Keys() {
+			spec, ok := keys[key.Name()]
+			if !ok || !spec.boolean {
+				continue
+			}
+			value := key.String()
+			if value == "" || validateBool(value) != nil {
+				continue
+			}
+			normalized := boolToString(parseBool(value))
+			if normalized != value {
+				key.SetValue(normalized)
+				changes = append(changes, fmt.Sprintf("set [%s] %s = %s (was %s)", section.Name(), key.Name(), normalized, value))
+			}
+		}
+	}
+
+	return changes
+}
+
+func sortedDefaults(section string) []string {
+	keys := make(map[string]configKey, len(defaultConfig[section]))
+	for key := range defaultConfig[section] {
+		keys[key] = configKey{}
+	}
+	return sortedKeys(keys)
+}
+
+func configVersion(cfg *ini.File) int {
+	version, _ := strconv.Atoi(cfg.Section("meta").Key("version").String())
+	return version
+}
+
+// migrateConfig runs the migrations cfg is missing and records the new
+// version. It returns the changes made.
+func migrateConfig(cfg *ini.File) []ConfigChange {
+	current := configVersion(cfg)
+	if current >= ConfigVersion {
+		return nil
+	}
+
+	var changes []ConfigChange
+	for _, migration := range configMigrations {
+		if migration.version <= current {
+			continue
+		}
+		for _, change := range migration.apply(cfg) {
+			changes = append(changes, ConfigChange{Version: migration.version, Change: change})
+		}
+	}
+
+	cfg.Section("meta").Key("version").SetValue(strconv.Itoa(ConfigVersion))
+	changes = append(changes, ConfigChange{
+		Version: ConfigVersion,
+		Change:  fmt.Sprintf("set [meta] version = %d (was %d)", ConfigVersion, current),
+	})
+	return changes
+}
+
+// backupConfig copies the config file next to itself before a migration
+// rewrites it, and returns the copy's path.
+func backupConfig(version int) (string, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+
+	backup := fmt.Sprintf("%s.v%d-%s.bak", configFile, version, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backup, data, configMode); err != nil {
+		return "", fmt.Errorf("unable to back up config file: %w", err)
+	}
+	return backup, nil
+}
+
+// MigrateConfig brings the config file to ConfigVersion, backing up the
+// original first. With dryRun nothing is written and the changes are only
+// returned. The backup path is empty when nothing was written.
+func MigrateConfig(dryRun bool) ([]ConfigChange, string, error) {
+	if dryRun {
+		cfg, err := ini.Load(configFile)
+		if err != nil {
+			return nil, "", err
+		}
+		return migrateConfig(cfg), "", nil
+	}
+
+	return writeConfig(func(cfg *ini.File) error { return nil })
+}
+
+// migrateOnLoad upgrades an outdated config file read by getIniFile, once
+// per process. When the file cannot be rewritten (e.g. without root) the
+// migrations are only applied to cfg, and a warning asks to run 'wiredoor
+// config migrate'. While the config lock is held the writer migrates the
+// file itself, so cfg is only migrated in memory.
+func migrateOnLoad(cfg *ini.File) *ini.File {
+	version := configVersion(cfg)
+
+	if version > ConfigVersion {
+		migrateWarningOnce.Do(func() {
+			utils.Terminal().Warnf("%s uses config version %d, newer than this wiredoor supports (%d); consider upgrading", configFile, version, ConfigVersion)
+		})
+		return cfg
+	}
+	if version == ConfigVersion {
+		return cfg
+	}
+	if !autoMigrate || configLocked.Load() || migrationTried.Swap(true) {
+		migrateConfig(cfg)
+		return cfg
+	}
+
+	changes, backup, err := MigrateConfig(false)
+	if err != nil {
+		migrateWarningOnce.Do(func() {
+			utils.Terminal().Warnf("%s uses config version %d and could not be migrated: %v", configFile, version, err)
+			utils.Terminal().Hint("Run 'wiredoor config migrate' with enough privileges.")
+		})
+		migrateConfig(cfg)
+		return cfg
+	}
+
+	slog.Info("Config file migrated", "file", configFile, "from", version, "to", ConfigVersion, "changes", len(changes), "backup", backup)
+
+	migrated, err := ini.Load(configFile)
+	if err != nil {
+		return cfg
+	}
+	return migrated
+}
@@ -0,0 +1,69 @@
+package wiredoor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// useConfigFile points the package at a config file in a temporary
+// directory for the duration of the test, and hides the WIREDOOR_*
+// variables of the environment running the tests.
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, "WIREDOOR_") {
+			t.Setenv(name, "")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "config.ini")
+	if err := os.WriteFile(path, []byte(content), configMode); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := configFile
+	SetConfigLocation(path)
+	t.Cleanup(func() { SetConfigLocation(previous) })
+
+	return path
+}
+
+// Storing a token through token_command in an unversioned file used to
+// migrate the file again from the helper while the lock was held.
+func TestSetTokenWithTokenCommandOnOutdatedConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token_command runs through sh")
+	}
+
+	path := useConfigFile(t, "[server]\nurl = https://wiredoor.example.com\ntoken_command = true\n")
+
+	done := make(chan error, 1)
+	go func() { done <- SetConfigValue("server.token", "new-token") }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("SetConfigValue: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("SetConfigValue did not return; the config lock is taken twice")
+	}
+
+	cfg, err := ini.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := configVersion(cfg); version != ConfigVersion {
+		t.Errorf("version = %d, want %d", version, ConfigVersion)
+	}
+	if token := cfg.Section("server").Key("token").String(); token != "" {
+		t.Errorf("token = %q, want it kept out of the file", token)
+	}
+}
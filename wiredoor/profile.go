@@ -0,0 +1,200 @@
+package wiredoor
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// DefaultProfile is the name of the [server] section. Other profiles are
+// stored in [profile <name>] sections with the same keys, e.g.
+//
+//	[profile staging]
+//	url = https://staging.wiredoor.example.com
+//	token = ...
+//
+// The profile used when none is selected is set in [context] current.
+const DefaultProfile = "default"
+
+const profileSectionPrefix = "profile "
+
+// profile is the profile selected with --profile, empty when not given.
+var profile string
+
+// Profile describes a server profile for 'wiredoor context list'.
+type Profile struct {
+	Name      string `json:"name"`
+	Url       string `json:"url"`
+	Interface string `json:"interface"`
+	Current   bool   `json:"current"`
+}
+
+// SetProfile selects the profile used by this process, overriding the
+// WIREDOOR_PROFILE environment variable and [context] current.
+func SetProfile(name string) {
+	profile = name
+}
+
+// ActiveProfile returns the profile in use: --profile, then
+// WIREDOOR_PROFILE, then [context] current, then the default profile.
+func ActiveProfile() string {
+	cfg, err := getIniFile()
+	if err != nil {
+		cfg = ini.Empty()
+	}
+	return activeProfile(cfg)
+}
+
+func activeProfile(cfg *ini.File) string {
+	if profile != "" {
+		return profile
+	}
+	if env := strings.TrimSpace(os.Getenv("WIREDOOR_PROFILE")); env != "" {
+		return env
+	}
+	if current := cfg.Section("context").Key("current").String(); current != "" {
+		return current
+	}
+	return DefaultProfile
+}
+
+func profileSectionName(name string) string {
+	if name == "" || name == DefaultProfile {
+		return "server"
+	}
+	return profileSectionPrefix + name
+}
+
+// sectionProfile returns the profile stored in section, the reverse of
+// profileSectionName.
+func sectionProfile(section *ini.Section) string {
+	if name, ok := strings.CutPrefix(section.Name(), profileSectionPrefix); ok {
+		return name
+	}
+	return DefaultProfile
+}
+
+// serverSection returns the section of the active profile. A profile that
+// does not exist yet is created empty, so it can be written to.
+func serverSection(cfg *ini.File) *ini.Section {
+	return cfg.Section(profileSectionName(activeProfile(cfg)))
+}
+
+// CheckProfile reports an error when the active profile is not defined.
+func CheckProfile() error {
+	cfg, err := getIniFile()
+	if err != nil {
+		return err
+	}
+
+	name := activeProfile(cfg)
+	if name != DefaultProfile && !cfg.HasSection(profileSectionName(name)) {
+		return fmt.Errorf("profile %q does not exist; run 'wiredoor context list' to see the available ones", name)
+	}
+	return nil
+}
+
+func ListProfiles() ([]Profile, error) {
+	cfg, err := getIniFile()
+	if err != nil {
+		return nil, err
+	}
+
+	active := activeProfile(cfg)
+	profiles := []Profile{{
+		Name:      DefaultProfile,
+		Url:       cfg.Section("server").Key("url").String(),
+		Interface: tunnelName(cfg.Section("server")),
+		Current:   active == DefaultProfile,
+	}}
+
+	for _, section := range cfg.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), profileSectionPrefix)
+		if !ok {
+			continue
+		}
+		profiles = append(profiles, Profile{
+			Name:      name,
+			Url:       section.Key("url").String(),
+			Interface: tunnelName(section),
+			Current:   active == name,
+		})
+	}
+
+	slices.SortStableFunc(profiles[1:], func(a, b Profile) int { return strings.Compare(a.Name, b.Name) })
+
+	return profiles, nil
+}
+
+// ValidateProfileName checks that name can be used as a profile name.
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if strings.ContainsAny(name, " \t[]=;#") {
+		return fmt.Errorf("invalid profile name %q: spaces and []=;# are not allowed", name)
+	}
+	return nil
+}
+
+// AddProfile creates or updates the profile name with a server URL and
+// token. A non-empty iface sets the WireGuard interface of the profile.
+func AddProfile(name string, url string, token string, iface string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if iface != "" {
+		if err := ValidateInterfaceName(iface); err != nil {
+			return err
+		}
+	}
+
+	return updateConfig(func(cfg *ini.File) error {
+		section := cfg.Section(profileSectionName(name))
+		section.Key("url").SetValue(url)
+		if iface != "" {
+			section.Key("interface").SetValue(iface)
+		}
+		return storeToken(section, token)
+	})
+}
+
+// UseProfile makes name the profile used when none is selected.
+func UseProfile(name string) error {
+	return updateConfig(func(cfg *ini.File) error {
+		if name != DefaultProfile && !cfg.HasSection(profileSectionName(name)) {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+
+		if name == DefaultProfile {
+			cfg.Section("context").DeleteKey("current")
+		} else {
+			cfg.Section("context").Key("current").SetValue(name)
+		}
+		return nil
+	})
+}
+
+// RemoveProfile deletes the profile name. The default profile cannot be
+// removed; when name is the current profile, the default one becomes
+// current.
+func RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the default profile cannot be removed")
+	}
+
+	return updateConfig(func(cfg *ini.File) error {
+		if !cfg.HasSection(profileSectionName(name)) {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+		cfg.DeleteSection(profileSectionName(name))
+
+		if cfg.Section("context").Key("current").String() == name {
+			cfg.Section("context").DeleteKey("current")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+package wiredoor
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestExplicitProxyHonorsNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", ".internal.example.com")
+	t.Setenv("no_proxy", "")
+
+	proxy, err := proxyFunc("socks5h://proxy.example.com:1080")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://wiredoor.example.com/api/cli/node", "socks5h://proxy.example.com:1080"},
+		{"https://wiredoor.internal.example.com/api/cli/node", ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		if (got == nil && tt.want != "") || (got != nil && got.String() != tt.want) {
+			t.Errorf("%s: proxy = %v, want %q", tt.url, got, tt.want)
+		}
+	}
+}
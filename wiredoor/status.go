@@ -1,60 +1,95 @@
 package wiredoor
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"time"
 
 	"github.com/wiredoor/wiredoor-cli/utils"
 )
 
-func Status() {
+func Status(ctx context.Context) {
+	if err := CheckTunnelOwner(); err != nil {
+		utils.Terminal().Errorf("%v", err)
+		return
+	}
+
 	if !WireguardInterfaceExists() {
-		utils.Terminal().Errorf("Wireguard interface %s is not active.", utils.TunnelName)
+		utils.Terminal().Errorf("Wireguard interface %s is not active.", TunnelName())
 		utils.Terminal().Hint("Run 'wiredoor connect' to establish the tunnel.")
 		return
 	}
 
-	if !CheckWiredoorServer(true) {
+	if !CheckWiredoorServer(ctx, true) {
 		utils.Terminal().Errorf("Tunnel seems active, but Wiredoor server unreachable.")
 		utils.Terminal().Hint("Try running 'wiredoor connect' again or check server availability.")
 		return
 	}
 
-	node := GetNode()
-	// services := GetServices()
+	node, err := GetNode(ctx)
+
+	if err != nil {
+		utils.Terminal().Errorf("Unable to retrieve node information: %v", err)
+		return
+	}
+
+	// The HEALTH column is only shown when the daemon probes backends.
+	var health *HealthState
+	if parseBool(GetHealthConfig().Enabled) {
+		state, err := LoadHealthState()
+		if err != nil {
+			utils.Terminal().Warnf("%v", err)
+		}
+		health = &state
+	}
 
-	printNodeInfoDetails(node)
+	utils.Terminal().Render(node, func() {
+		printNodeInfoDetails(node, health)
+	})
 }
 
-func Health() {
+func Health(ctx context.Context) {
 	if !WireguardInterfaceExists() {
-		utils.Terminal().Errorf("WireGuard interface " + utils.TunnelName + " is not active.")
+		utils.Terminal().Errorf("WireGuard interface %s is not active.", TunnelName())
 		os.Exit(1)
 		return
 	}
-	if !CheckWiredoorServer(true) {
+	config, ok := checkWiredoorServer(ctx, true)
+	if !ok {
 		os.Exit(1)
 		return
 	}
+	utils.Terminal().Render(config, nil)
 	os.Exit(0)
 }
 
-func WatchHealt() {
+func WatchHealt(ctx context.Context) {
 	// log.Println("WatchHealt")
 	if ExistWireguardConfigFile() {
 		if !WireguardInterfaceExists() {
-			node := GetNode()
+			node, err := GetNode(ctx)
+
+			if err != nil {
+				slog.Warn("Unable to retrieve node information", "error", err)
+				return
+			}
 
 			if node.Enabled {
-				Connect(ConnectionConfig{})
+				if err := Connect(ctx, ConnectionConfig{}); err != nil {
+					slog.Warn("Unable to reconnect the tunnel", "error", err)
+				}
 			}
 			return
 		}
 
-		if !CheckWiredoorServer(false) {
+		if !CheckWiredoorServer(ctx, false) {
 			RestartTunnel()
+			return
 		}
+
+		probeServices(ctx)
 	}
 }
 
@@ -63,41 +98,61 @@ func WireguardInterfaceExists() bool {
 	return interfaceExists()
 }
 
-func CheckWiredoorServer(debug bool) bool {
-	ip := utils.LocalServerIP(getInterfaceName())
+// TunnelIP returns the address of this node on the Wiredoor tunnel, or an
+// empty string when the tunnel is down.
+func TunnelIP() string {
+	return utils.LocalTunnelIP(tunnelInterface())
+}
+
+func CheckWiredoorServer(ctx context.Context, debug bool) bool {
+	_, ok := checkWiredoorServer(ctx, debug)
+	return ok
+}
+
+// checkWiredoorServer probes the server through the tunnel. With debug set
+// it also fetches the API configuration and returns it.
+func checkWiredoorServer(ctx context.Context, debug bool) (ApiConfig, bool) {
+	ip := utils.LocalServerIP(tunnelInterface())
 
 	if !utils.CheckPort(ip, 443) {
-		return false
+		return ApiConfig{}, false
 	} else {
 		if debug {
-			config := GetApiConfig()
+			config, err := GetApiConfig(ctx)
+			if err != nil {
+				utils.Terminal().Errorf("Unable to retrieve API configuration: %v", err)
+				return ApiConfig{}, false
+			}
 			utils.Terminal().FinalizeProgress()
 			utils.Terminal().Section("Connection successful to: " + config.VPN_HOST)
+			return config, true
 		}
-		return true
+		return ApiConfig{}, true
 	}
 }
 
-func printNodeInfoDetails(node NodeInfo) {
+func printNodeInfoDetails(node NodeInfo, health *HealthState) {
 	utils.Terminal().Println("")
 	if node.IsGateway {
-		if node.GatewayNetwork != "" && len(node.GatewayNetworks) == 0 {
+		if node.legacyGateway {
 			utils.Terminal().Printf("Using legacy gatewayNetwork field. Consider updating your Wiredoor Server.")
-
-			utils.Terminal().KV("Gateway", fmt.Sprintf("%s (%s)", node.Name, node.Address))
-			utils.Terminal().KV("Subnet", node.GatewayNetwork)
 		}
-		if len(node.GatewayNetworks) > 0 {
-			var entries []string
-			for _, net := range node.GatewayNetworks {
-				if !utils.InterfaceExists(net.Interface) {
-					utils.Terminal().Printf("⚠️ Interface \"%s\" does not exist on this system.\n", net.Interface)
-				}
 
-				entries = append(entries, fmt.Sprintf("%s: %s", net.Interface, net.Subnet))
+		var entries []string
+		for _, net := range node.GatewayNetworks {
+			if net.Interface == "" {
+				entries = append(entries, net.Subnet)
+				continue
 			}
+			if !utils.InterfaceExists(net.Interface) {
+				utils.Terminal().Printf("⚠️ Interface \"%s\" does not exist on this system.\n", net.Interface)
+			}
+
+			entries = append(entries, fmt.Sprintf("%s: %s", net.Interface, net.Subnet))
+		}
 
-			utils.Terminal().KV("Gateway", fmt.Sprintf("%s (%s)", node.Name, node.Address))
+		utils.Terminal().KV("Gateway", fmt.Sprintf("%s (%s)", node.Name, node.Address))
+		if len(entries) > 0 {
 			utils.Terminal().KV("Subnet", entries)
 		}
 	} else {
@@ -110,8 +165,8 @@ func printNodeInfoDetails(node NodeInfo) {
 	utils.Terminal().Println("")
 	if len(node.HttpServices) > 0 || len(node.TcpServices) > 0 {
 		utils.Terminal().Section("Services:")
-		PrintHttpServices(node.HttpServices, node.IsGateway)
-		PrintTcpServices(node.TcpServices, node.IsGateway)
+		printHttpServices(node.HttpServices, node.IsGateway, health)
+		printTcpServices(node.TcpServices, node.IsGateway, health)
 	} else {
 		utils.Terminal().Section("No services exposed yet.")
 		utils.Terminal().Hint("Use 'wiredoor http' or 'wiredoor tcp' to expose a service.")
@@ -0,0 +1,278 @@
+package wiredoor
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type TLSOptions struct {
+	CAFile    string // extra PEM bundle trusted in addition to the system roots
+	PinSHA256 string // base64 SHA-256 of the server leaf SubjectPublicKeyInfo
+	Insecure  bool   // skip chain verification (the pin, if any, is still enforced)
+
+	ClientCert string // PEM client certificate for mutual TLS
+	ClientKey  string // PEM private key matching ClientCert
+}
+
+// CertificateInfo describes the certificate presented by a Wiredoor server.
+type CertificateInfo struct {
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+	Fingerprint string // SPKI pin, same format as [server] pin_sha256
+	Trusted     bool   // chain verifies against the system roots and ca_file
+	PinMatches  bool   // fingerprint equals the configured pin
+}
+
+// NewTLSConfig builds the client TLS configuration for API calls.
+// Verification is on unless opts.Insecure is set. When a pin is configured
+// without a CA bundle the pin alone authenticates the server, which is what
+// makes trust-on-first-use work with self-signed certificates.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		pool, err := loadCertPool(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	certificates, err := loadClientCertificate(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.Certificates = certificates
+
+	pin := ""
+	if opts.PinSHA256 != "" {
+		normalized, err := NormalizePin(opts.PinSHA256)
+		if err != nil {
+			return nil, err
+		}
+		pin = normalized
+	}
+
+	if opts.Insecure || (pin != "" && opts.CAFile == "") {
+		config.InsecureSkipVerify = true
+	}
+
+	if pin != "" {
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			got := SPKIFingerprint(state.PeerCertificates[0])
+			if subtle.ConstantTimeCompare([]byte(got), []byte(pin)) != 1 {
+				return &PinMismatchError{Expected: pin, Got: got}
+			}
+			return nil
+		}
+	}
+
+	return config, nil
+}
+
+// PinMismatchError is returned when the server key does not match the
+// configured pin_sha256.
+type PinMismatchError struct {
+	Expected string
+	Got      string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("server certificate does not match pinned key (expected %s, got %s)", e.Expected, e.Got)
+}
+
+// SPKIFingerprint returns the base64 SHA-256 of the certificate public key.
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// NormalizePin accepts a pin as plain base64, "sha256//<base64>" (curl
+// style) or hex with optional colons, and returns it as plain base64.
+func NormalizePin(pin string) (string, error) {
+	pin = strings.TrimSpace(pin)
+	pin = strings.TrimPrefix(pin, "sha256//")
+	pin = strings.TrimPrefix(pin, "sha256/")
+
+	if raw, err := base64.StdEncoding.DecodeString(pin); err == nil && len(raw) == sha256.Size {
+		return pin, nil
+	}
+
+	if raw, err := hex.DecodeString(strings.ReplaceAll(pin, ":", "")); err == nil && len(raw) == sha256.Size {
+		return base64.StdEncoding.EncodeToString(raw), nil
+	}
+
+	return "", fmt.Errorf("invalid pin_sha256 %q: expected a base64 or hex SHA-256 digest", pin)
+}
+
+// InspectServerCertificate connects to server without verifying it and
+// reports who answered, so the user can decide whether to trust it. The
+// connection goes through proxy, like API calls do.
+func InspectServerCertificate(server string, opts TLSOptions, proxy string) (CertificateInfo, error) {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return CertificateInfo{}, fmt.Errorf("invalid server URL: %s", server)
+	}
+
+	if u.Scheme != "https" {
+		return CertificateInfo{}, fmt.Errorf("server URL %s does not use https", server)
+	}
+
+	host := u.Hostname()
+
+	certificates, err := loadClientCertificate(opts)
+	if err != nil {
+		return CertificateInfo{}, err
+	}
+
+	proxyFn, err := proxyFunc(proxy)
+	if err != nil {
+		return CertificateInfo{}, err
+	}
+
+	var certs []*x509.Certificate
+
+	transport := &http.Transport{
+		Proxy: proxyFn,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         host,
+			Certificates:       certificates,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				certs = state.PeerCertificates
+				return nil
+			},
+		},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	defer transport.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Only the handshake matters; whatever the server answers is ignored.
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, (&url.URL{Scheme: "https", Host: u.Host, Path: "/"}).String(), nil)
+	if err != nil {
+		return CertificateInfo{}, err
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil && len(certs) == 0 {
+		if isHandshakeRejected(err) {
+			return CertificateInfo{}, fmt.Errorf("%w: %v", ErrClientCertificateRejected, err)
+		}
+		return CertificateInfo{}, err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	if len(certs) == 0 {
+		return CertificateInfo{}, errors.New("server presented no certificate")
+	}
+
+	leaf := certs[0]
+	info := CertificateInfo{
+		Subject:     leaf.Subject.String(),
+		Issuer:      leaf.Issuer.String(),
+		NotAfter:    leaf.NotAfter,
+		Fingerprint: SPKIFingerprint(leaf),
+	}
+
+	verifyOpts := x509.VerifyOptions{DNSName: host, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		verifyOpts.Intermediates.AddCert(cert)
+	}
+	if opts.CAFile != "" {
+		if pool, err := loadCertPool(opts.CAFile); err == nil {
+			verifyOpts.Roots = pool
+		}
+	}
+	_, verifyErr := leaf.Verify(verifyOpts)
+	info.Trusted = verifyErr == nil
+
+	if opts.PinSHA256 != "" {
+		if pin, err := NormalizePin(opts.PinSHA256); err == nil {
+			info.PinMatches = pin == info.Fingerprint
+		}
+	}
+
+	return info, nil
+}
+
+// loadClientCertificate loads the mutual TLS key pair, if one is configured.
+func loadClientCertificate(opts TLSOptions) ([]tls.Certificate, error) {
+	if opts.ClientCert == "" && opts.ClientKey == "" {
+		return nil, nil
+	}
+
+	if opts.ClientCert == "" || opts.ClientKey == "" {
+		return nil, errors.New("client_cert and client_key must be set together")
+	}
+
+	pair, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load client certificate: %w", err)
+	}
+
+	return []tls.Certificate{pair}, nil
+}
+
+// isHandshakeRejected reports whether the server aborted the TLS handshake
+// with a certificate related alert, which is how a missing or unaccepted
+// client certificate shows up on the client side.
+func isHandshakeRejected(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "remote error" {
+		return false
+	}
+
+	msg := opErr.Err.Error()
+	return strings.Contains(msg, "certificate") || strings.Contains(msg, "access denied")
+}
+
+// loadCertPool returns the system roots extended with the PEM bundle at path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// ValidateClientCertificate checks that a key pair, as it would be written
+// to config.ini, can be loaded.
+func ValidateClientCertificate(cert string, key string) error {
+	_, err := loadClientCertificate(TLSOptions{
+		ClientCert: configRelativePath(cert),
+		ClientKey:  configRelativePath(key),
+	})
+	return err
+}
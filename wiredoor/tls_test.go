@@ -0,0 +1,43 @@
+package wiredoor
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A daemon keeps running across certificate rotations, so the shared
+// client must not keep using the CA bundle it first loaded.
+func TestSharedHTTPClientReloadsChangedCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := TLSOptions{CAFile: caFile}
+
+	first, err := sharedHTTPClient(opts, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := sharedHTTPClient(opts, ""); again != first {
+		t.Error("unchanged CA bundle: got a new client, want the cached one")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(caFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if rotated, _ := sharedHTTPClient(opts, ""); rotated == first {
+		t.Error("changed CA bundle: got the cached client, want a new one")
+	}
+}
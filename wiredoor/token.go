@@ -0,0 +1,327 @@
+package wiredoor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// The node token of a profile comes from, in order:
+//
+//   - WIREDOOR_SERVER_TOKEN
+//   - token_command, a credential helper run as '<command> get' that prints
+//     the token. New tokens are handed to '<command> store' on stdin.
+//   - token, in plain text or encrypted as enc:<mode>:<base64> when
+//     token_encryption is machine-id or passphrase.
+const (
+	TokenEncryptionMachineID  = "machine-id"
+	TokenEncryptionPassphrase = "passphrase"
+)
+
+// TokenPassphraseEnv holds the passphrase of tokens encrypted with the
+// passphrase mode.
+const TokenPassphraseEnv = "WIREDOOR_TOKEN_PASSPHRASE"
+
+const encryptedTokenPrefix = "enc:"
+
+// passphraseIterations is the PBKDF2 work factor for passphrase keys. It
+// is a variable so tests can lower it.
+var passphraseIterations = 600000
+
+// tokenCacheTTL bounds how long the daemon reuses the output of
+// token_command, so rotated tokens are picked up.
+const tokenCacheTTL = 5 * time.Minute
+
+type cachedToken struct {
+	token   string
+	fetched time.Time
+}
+
+var (
+	tokenCacheMu sync.Mutex
+	tokenCache   = map[string]cachedToken{}
+
+	// decryptedTokens maps encrypted token values to their plain token, so
+	// the key derivation runs once per process rather than per request.
+	decryptedTokens = map[string]string{}
+)
+
+// ValidateTokenEncryption checks a token_encryption value. Empty and "none"
+// store the token in plain text.
+func ValidateTokenEncryption(mode string) error {
+	switch mode {
+	case "", "none", TokenEncryptionMachineID, TokenEncryptionPassphrase:
+		return nil
+	default:
+		return fmt.Errorf("invalid token encryption %q: use machine-id, passphrase or none", mode)
+	}
+}
+
+// ResolveToken returns the node token of the server config, running the
+// credential helper or decrypting the stored token when needed.
+func (s ServerConfig) ResolveToken() (string, error) {
+	if s.TokenCommand != "" && os.Getenv(EnvName("server", "token")) == "" {
+		return runTokenHelper(s.TokenCommand, ActiveProfile(), s.Url)
+	}
+	return decryptToken(s.Token)
+}
+
+// SetTokenEncryption changes how the token of the active profile is stored
+// and rewrites it accordingly.
+func SetTokenEncryption(mode string) error {
+	if err := ValidateTokenEncryption(mode); err != nil {
+		return err
+	}
+
+	return updateConfig(func(cfg *ini.File) error {
+		section := serverSection(cfg)
+		if section.Key("token_command").String() != "" {
+			return errors.New("token_command is set, so the token is not stored in the config file")
+		}
+
+		token, err := decryptToken(section.Key("token").String())
+		if err != nil {
+			return err
+		}
+
+		if mode == "none" {
+			mode = ""
+		}
+		section.Key("token_encryption").SetValue(mode)
+
+		return storeToken(section, token)
+	})
+}
+
+// storeToken saves token in section as its token_command and
+// token_encryption settings require. It runs under the config lock, so it
+// must not read the config file again.
+func storeToken(section *ini.Section, token string) error {
+	if command := section.Key("token_command").String(); command != "" {
+		if err := storeTokenHelper(command, sectionProfile(section), section.Key("url").String(), token); err != nil {
+			return err
+		}
+		section.Key("token").SetValue("")
+		return nil
+	}
+
+	value, err := encryptToken(token, section.Key("token_encryption").String())
+	if err != nil {
+		return err
+	}
+	section.Key("token").SetValue(value)
+	return nil
+}
+
+func runTokenHelper(command string, profile string, url string) (string, error) {
+	key := profile + "\x00" + command
+
+	tokenCacheMu.Lock()
+	defer tokenCacheMu.Unlock()
+
+	if cached, ok := tokenCache[key]; ok && time.Since(cached.fetched) < tokenCacheTTL {
+		return cached.token, nil
+	}
+
+	out, err := tokenHelper(command, "get", profile, url, nil).Output()
+	if err != nil {
+		return "", fmt.Errorf("token_command failed: %w", helperError(err))
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errors.New("token_command printed no token")
+	}
+
+	tokenCache[key] = cachedToken{token: token, fetched: time.Now()}
+	return token, nil
+}
+
+func storeTokenHelper(command string, profile string, url string, token string) error {
+	if err := tokenHelper(command, "store", profile, url, strings.NewReader(token+"\n")).Run(); err != nil {
+		return fmt.Errorf("token_command store failed: %w", helperError(err))
+	}
+
+	tokenCacheMu.Lock()
+	clear(tokenCache)
+	tokenCacheMu.Unlock()
+
+	return nil
+}
+
+// tokenHelper builds the command line of a credential helper, run through
+// the shell like git credential helpers. The helper learns the profile and
+// server from WIREDOOR_PROFILE and WIREDOOR_SERVER_URL.
+func tokenHelper(command string, action string, profile string, url string, stdin *strings.Reader) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command+" "+action)
+	} else {
+		cmd = exec.Command("sh", "-c", command+` "$@"`, "sh", action)
+	}
+
+	cmd.Env = append(os.Environ(), "WIREDOOR_PROFILE="+profile, EnvName("server", "url")+"="+url)
+	cmd.Stderr = os.Stderr
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	return cmd
+}
+
+func helperError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("exit status %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// encryptToken returns token encrypted with AES-256-GCM as
+// enc:<mode>:<base64(salt|nonce|ciphertext)>, or token itself when mode is
+// empty or "none".
+func encryptToken(token string, mode string) (string, error) {
+	if mode == "" || mode == "none" || token == "" {
+		return token, nil
+	}
+	if err := ValidateTokenEncryption(mode); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	aead, err := tokenCipher(mode, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(append(append([]byte{}, salt...), nonce...), nonce, []byte(token), []byte(mode))
+	return encryptedTokenPrefix + mode + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken returns the plain token of a stored token value.
+func decryptToken(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedTokenPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	tokenCacheMu.Lock()
+	defer tokenCacheMu.Unlock()
+
+	if token, ok := decryptedTokens[value]; ok {
+		return token, nil
+	}
+
+	token, err := openToken(rest)
+	if err != nil {
+		return "", err
+	}
+	decryptedTokens[value] = token
+	return token, nil
+}
+
+// openToken decrypts the <mode>:<base64> part of an encrypted token.
+func openToken(rest string) (string, error) {
+	mode, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("invalid encrypted token")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < 16 {
+		return "", errors.New("invalid encrypted token")
+	}
+	salt := sealed[:16]
+
+	aead, err := tokenCipher(mode, salt)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < 16+aead.NonceSize() {
+		return "", errors.New("invalid encrypted token")
+	}
+	nonce := sealed[16 : 16+aead.NonceSize()]
+
+	token, err := aead.Open(nil, nonce, sealed[16+aead.NonceSize():], []byte(mode))
+	if err != nil {
+		if mode == TokenEncryptionPassphrase {
+			return "", fmt.Errorf("unable to decrypt the token: wrong %s", TokenPassphraseEnv)
+		}
+		return "", errors.New("unable to decrypt the token: it was encrypted on another machine")
+	}
+	return string(token), nil
+}
+
+func tokenCipher(mode string, salt []byte) (cipher.AEAD, error) {
+	var key []byte
+
+	switch mode {
+	case TokenEncryptionMachineID:
+		id, err := machineID()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the machine ID: %w", err)
+		}
+		key, err = hkdf.Key(sha256.New, []byte(id), salt, "wiredoor node token", 32)
+		if err != nil {
+			return nil, err
+		}
+	case TokenEncryptionPassphrase:
+		passphrase := os.Getenv(TokenPassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("the token is encrypted with a passphrase; set %s", TokenPassphraseEnv)
+		}
+		var err error
+		key, err = pbkdf2.Key(sha256.New, passphrase, salt, passphraseIterations, 32)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown token encryption %q", mode)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// machineIDFiles are read in order for the machine-id key on Unix.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+func readMachineIDFile() (string, error) {
+	for _, file := range machineIDFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if id := string(bytes.TrimSpace(data)); id != "" {
+			return id, nil
+		}
+	}
+	return "", errors.New("no machine ID found in " + strings.Join(machineIDFiles, " or "))
+}
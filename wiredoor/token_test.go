@@ -0,0 +1,209 @@
+package wiredoor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fastTokenKeys lowers the PBKDF2 work factor and empties the token caches
+// for the duration of the test.
+func fastTokenKeys(t *testing.T) {
+	t.Helper()
+
+	iterations := passphraseIterations
+	passphraseIterations = 1000
+	t.Cleanup(func() { passphraseIterations = iterations })
+
+	resetTokenCaches := func() {
+		tokenCacheMu.Lock()
+		clear(tokenCache)
+		clear(decryptedTokens)
+		tokenCacheMu.Unlock()
+	}
+	resetTokenCaches()
+	t.Cleanup(resetTokenCaches)
+}
+
+// useMachineID makes the machine-id mode use id as the machine ID.
+func useMachineID(t *testing.T, id string) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("the machine ID is only read from a file on Linux")
+	}
+
+	file := filepath.Join(t.TempDir(), "machine-id")
+	if err := os.WriteFile(file, []byte(id+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := machineIDFiles
+	machineIDFiles = []string{file}
+	t.Cleanup(func() { machineIDFiles = files })
+}
+
+func TestTokenEncryptionRoundTrip(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantPrefix string
+	}{
+		{mode: "", wantPrefix: "node-token"},
+		{mode: "none", wantPrefix: "node-token"},
+		{mode: TokenEncryptionPassphrase, wantPrefix: "enc:passphrase:"},
+		{mode: TokenEncryptionMachineID, wantPrefix: "enc:machine-id:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			fastTokenKeys(t)
+			t.Setenv(TokenPassphraseEnv, "correct horse")
+			if tt.mode == TokenEncryptionMachineID {
+				useMachineID(t, "machine-a")
+			}
+
+			stored, err := encryptToken("node-token", tt.mode)
+			if err != nil {
+				t.Fatalf("encryptToken: %v", err)
+			}
+			if !strings.HasPrefix(stored, tt.wantPrefix) {
+				t.Errorf("stored token = %q, want prefix %q", stored, tt.wantPrefix)
+			}
+
+			token, err := decryptToken(stored)
+			if err != nil || token != "node-token" {
+				t.Errorf("decryptToken = %q, %v; want node-token", token, err)
+			}
+		})
+	}
+}
+
+func TestDecryptTokenErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		passphrase string // when decrypting
+		machineID  string // when decrypting
+		wantErr    string
+	}{
+		{name: "wrong passphrase", mode: TokenEncryptionPassphrase, passphrase: "wrong", wantErr: "wrong " + TokenPassphraseEnv},
+		{name: "no passphrase", mode: TokenEncryptionPassphrase, wantErr: "set " + TokenPassphraseEnv},
+		{name: "other machine", mode: TokenEncryptionMachineID, machineID: "machine-b", wantErr: "encrypted on another machine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastTokenKeys(t)
+			t.Setenv(TokenPassphraseEnv, "correct horse")
+			if tt.mode == TokenEncryptionMachineID {
+				useMachineID(t, "machine-a")
+			}
+
+			stored, err := encryptToken("node-token", tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Setenv(TokenPassphraseEnv, tt.passphrase)
+			if tt.machineID != "" {
+				useMachineID(t, tt.machineID)
+			}
+
+			if _, err := decryptToken(stored); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decryptToken error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, value := range []string{"enc:passphrase", "enc:passphrase:not-base64!", "enc:passphrase:c2hvcnQ=", "enc:rot13:" + strings.Repeat("A", 64)} {
+		if _, err := decryptToken(value); err == nil {
+			t.Errorf("decryptToken(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+// The key derivation is slow by design, so a token is only decrypted once.
+func TestDecryptTokenIsMemoised(t *testing.T) {
+	fastTokenKeys(t)
+	t.Setenv(TokenPassphraseEnv, "correct horse")
+
+	stored, err := encryptToken("node-token", TokenEncryptionPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptToken(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the passphrase the token can only come from the cache.
+	t.Setenv(TokenPassphraseEnv, "")
+	if token, err := decryptToken(stored); err != nil || token != "node-token" {
+		t.Errorf("second decryptToken = %q, %v; want node-token", token, err)
+	}
+}
+
+func TestTokenCommandCaching(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token_command runs through sh")
+	}
+	fastTokenKeys(t)
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	helper := filepath.Join(dir, "helper")
+	script := "#!/bin/sh\necho \"$1\" >> " + calls + "\ncat > /dev/null\necho token-$WIREDOOR_PROFILE\n"
+	if err := os.WriteFile(helper, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	countCalls := func() int {
+		data, _ := os.ReadFile(calls)
+		return strings.Count(string(data), "\n")
+	}
+	get := func(profile string) string {
+		t.Helper()
+		token, err := runTokenHelper(helper, profile, "https://wiredoor.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	if token := get("default"); token != "token-default" {
+		t.Errorf("token = %q, want token-default", token)
+	}
+	get("default")
+	if n := countCalls(); n != 1 {
+		t.Errorf("helper ran %d times for the same profile, want 1", n)
+	}
+
+	if token := get("staging"); token != "token-staging" {
+		t.Errorf("token = %q, want token-staging", token)
+	}
+	if n := countCalls(); n != 2 {
+		t.Errorf("helper ran %d times for two profiles, want 2", n)
+	}
+
+	// Storing a new token invalidates the cached ones.
+	if err := storeTokenHelper(helper, "default", "https://wiredoor.example.com", "new"); err != nil {
+		t.Fatal(err)
+	}
+	get("default")
+	if n := countCalls(); n != 4 {
+		t.Errorf("helper ran %d times after a store, want 4", n)
+	}
+
+	// Cached tokens expire so the daemon picks up rotated ones.
+	tokenCacheMu.Lock()
+	for key, cached := range tokenCache {
+		cached.fetched = time.Now().Add(-tokenCacheTTL)
+		tokenCache[key] = cached
+	}
+	tokenCacheMu.Unlock()
+	get("default")
+	if n := countCalls(); n != 5 {
+		t.Errorf("helper ran %d times after the cache expired, want 5", n)
+	}
+}
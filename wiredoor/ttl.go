@@ -0,0 +1,98 @@
+package wiredoor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrExpired is returned by RemainingTTL when the expiry has already passed.
+var ErrExpired = errors.New("service expired")
+
+// ttlUnits are the units NormalizeTTL picks from, largest first.
+var ttlUnits = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// ParseTTL parses a service time-to-live: a Go duration ("90m", "1h30m")
+// or a whole number of days ("2d"). An empty ttl means no expiry and
+// parses to zero.
+func ParseTTL(ttl string) (time.Duration, error) {
+	ttl = strings.TrimSpace(ttl)
+	if ttl == "" {
+		return 0, nil
+	}
+
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(ttl, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(ttl)
+	}
+
+	switch {
+	case err != nil:
+		return 0, fmt.Errorf("invalid ttl %q: use a duration like 30m, 1h or 2d", ttl)
+	case d < time.Second:
+		return 0, fmt.Errorf("invalid ttl %q: must be at least 1s", ttl)
+	case d%time.Second != 0:
+		return 0, fmt.Errorf("invalid ttl %q: must be a whole number of seconds", ttl)
+	}
+
+	return d, nil
+}
+
+// NormalizeTTL validates ttl and rewrites it in the largest unit that
+// divides it exactly, e.g. "120m" becomes "2h" and "1h30m" becomes "90m".
+func NormalizeTTL(ttl string) (string, error) {
+	d, err := ParseTTL(ttl)
+	if err != nil || d == 0 {
+		return "", err
+	}
+
+	for _, unit := range ttlUnits {
+		if d%unit.size == 0 {
+			return strconv.FormatInt(int64(d/unit.size), 10) + unit.suffix, nil
+		}
+	}
+
+	return d.String(), nil
+}
+
+// RemainingTTL returns the ttl that makes a service expire at expiresAt
+// again, e.g. when it is re-exposed. It is empty when expiresAt is nil and
+// fails with ErrExpired when expiresAt has passed, so callers do not turn
+// an expired service into a permanent one.
+func RemainingTTL(expiresAt *time.Time) (string, error) {
+	if expiresAt == nil {
+		return "", nil
+	}
+
+	left := time.Until(*expiresAt).Round(time.Second)
+	if left < time.Second {
+		return "", ErrExpired
+	}
+
+	return NormalizeTTL(left.String())
+}
+
+// comparableTtl normalizes ttl so equal durations compare equal, and keeps
+// values it cannot parse as they are.
+func comparableTtl(ttl string) string {
+	normalized, err := NormalizeTTL(ttl)
+	if err != nil {
+		return ttl
+	}
+	return normalized
+}
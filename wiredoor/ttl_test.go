@@ -0,0 +1,136 @@
+package wiredoor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		ttl     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{ttl: "", want: 0},
+		{ttl: "  ", want: 0},
+		{ttl: "30s", want: 30 * time.Second},
+		{ttl: "90m", want: 90 * time.Minute},
+		{ttl: "1h30m", want: 90 * time.Minute},
+		{ttl: "2d", want: 48 * time.Hour},
+		{ttl: " 1h ", want: time.Hour},
+		{ttl: "0.5s", wantErr: true},
+		{ttl: "1.5s", wantErr: true},
+		{ttl: "500ms", wantErr: true},
+		{ttl: "0s", wantErr: true},
+		{ttl: "-1d", wantErr: true},
+		{ttl: "-5m", wantErr: true},
+		{ttl: "1.5d", wantErr: true},
+		{ttl: "d", wantErr: true},
+		{ttl: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ttl, func(t *testing.T) {
+			got, err := ParseTTL(tt.ttl)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTTL(%q) = %v, want an error", tt.ttl, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseTTL(%q) = %v, %v; want %v", tt.ttl, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTTL(t *testing.T) {
+	tests := []struct {
+		ttl     string
+		want    string
+		wantErr bool
+	}{
+		{ttl: "", want: ""},
+		{ttl: "2d", want: "2d"},
+		{ttl: "48h", want: "2d"},
+		{ttl: "120m", want: "2h"},
+		{ttl: "1h30m", want: "90m"},
+		{ttl: "3600s", want: "1h"},
+		{ttl: "61s", want: "61s"},
+		{ttl: "0.5s", wantErr: true},
+		{ttl: "-1d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ttl, func(t *testing.T) {
+			got, err := NormalizeTTL(tt.ttl)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeTTL(%q) = %q, want an error", tt.ttl, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeTTL(%q) = %q, %v; want %q", tt.ttl, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemainingTTL(t *testing.T) {
+	at := func(d time.Duration) *time.Time {
+		expiry := time.Now().Add(d)
+		return &expiry
+	}
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      string
+		wantErr   error
+	}{
+		{name: "no expiry", expiresAt: nil, want: ""},
+		{name: "days", expiresAt: at(48*time.Hour + 200*time.Millisecond), want: "2d"},
+		{name: "minutes", expiresAt: at(90*time.Minute + 200*time.Millisecond), want: "90m"},
+		{name: "rounded to seconds", expiresAt: at(10*time.Second + 200*time.Millisecond), want: "10s"},
+		{name: "under a second", expiresAt: at(300 * time.Millisecond), wantErr: ErrExpired},
+		{name: "expired", expiresAt: at(-24 * time.Hour), wantErr: ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RemainingTTL(tt.expiresAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemainingTTL error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RemainingTTL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparableTtl(t *testing.T) {
+	tests := []struct {
+		ttl  string
+		want string
+	}{
+		{ttl: "", want: ""},
+		{ttl: "1h30m", want: "90m"},
+		{ttl: "24h", want: "1d"},
+		{ttl: "2d", want: "2d"},
+		{ttl: "-1d", want: "-1d"},
+		{ttl: "0.5s", want: "0.5s"},
+		{ttl: "forever", want: "forever"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ttl, func(t *testing.T) {
+			if got := comparableTtl(tt.ttl); got != tt.want {
+				t.Errorf("comparableTtl(%q) = %q, want %q", tt.ttl, got, tt.want)
+			}
+		})
+	}
+}
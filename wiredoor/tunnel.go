@@ -0,0 +1,63 @@
+package wiredoor
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/wiredoor/wiredoor-cli/utils"
+	"gopkg.in/ini.v1"
+)
+
+// interfaceNamePattern matches the interface names accepted by wg-quick.
+var interfaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_=+.-]{1,15}$`)
+
+// TunnelName returns the WireGuard interface of the active profile: the
+// interface key of its section, or utils.TunnelName when not set. Give each
+// profile its own interface to keep several tunnels up at once.
+func TunnelName() string {
+	cfg, err := getIniFile()
+	if err != nil {
+		cfg = ini.Empty()
+	}
+	if name := configValue(serverSection(cfg), "server", "interface"); name != "" {
+		return name
+	}
+	return utils.TunnelName
+}
+
+// tunnelName returns the interface stored in a profile section, ignoring
+// WIREDOOR_SERVER_INTERFACE which only applies to the profile in use.
+func tunnelName(section *ini.Section) string {
+	if name := section.Key("interface").String(); name != "" {
+		return name
+	}
+	return utils.TunnelName
+}
+
+// ValidateInterfaceName checks that name can be used as a WireGuard
+// interface name.
+func ValidateInterfaceName(name string) error {
+	if !interfaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid interface name %q: use up to 15 letters, digits or _=+.-", name)
+	}
+	return nil
+}
+
+// CheckTunnelOwner reports an error when the interface of the active profile
+// is up for another profile, which happens when both use the same interface.
+func CheckTunnelOwner() error {
+	owner := tunnelOwner()
+	if owner == "" || owner == ActiveProfile() || !WireguardInterfaceExists() {
+		return nil
+	}
+	return fmt.Errorf("interface %s is in use by profile %q; disconnect it first or set another interface for profile %q", TunnelName(), owner, ActiveProfile())
+}
+
+// tunnelInterface returns the system interface of the active profile's
+// tunnel, which differs from TunnelName on macOS (utunN).
+func tunnelInterface() string {
+	if iface := getInterfaceName(); iface != "" {
+		return iface
+	}
+	return TunnelName()
+}